@@ -0,0 +1,96 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+
+	"gitlab.com/vultour/steamcli/api/profile"
+	"gitlab.com/vultour/steamcli/objects"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Achievements returns the achievement progress of every client owning the
+// game, keyed by client ID
+// Cached progress is reused, as is the lack of it. Clients whose stats aren't
+// available are left out.
+func (a *Aggregator) Achievements(appid int) map[string]*objects.XMLPlayerStats {
+	return a.AchievementsContext(context.Background(), appid)
+}
+
+// AchievementsContext is like Achievements, the requests are cancelled once
+// ctx is done
+func (a *Aggregator) AchievementsContext(ctx context.Context, appid int) map[string]*objects.XMLPlayerStats {
+	ret := make(map[string]*objects.XMLPlayerStats)
+	fetched := false
+	for id, c := range a.Clients {
+		if _, owned := c.Profile.Games.Contains(appid); !owned {
+			continue
+		}
+		if s, cached := c.Profile.Achievements[appid]; cached {
+			if !s.Unavailable {
+				ret[id] = s
+			}
+			continue
+		}
+
+		s, err := c.GetAchievementsContext(ctx, appid)
+		if errors.Is(err, profile.ErrStatsNotFound) {
+			log.WithFields(log.Fields{
+				"id":    id,
+				"appid": appid,
+			}).Info("No achievements available")
+			a.cacheProfile(&c.Profile)
+			fetched = true
+			continue
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id":    id,
+				"appid": appid,
+				"err":   err,
+			}).Warn("Could not retrieve achievements")
+			continue
+		}
+		a.cacheProfile(&c.Profile)
+		fetched = true
+		ret[id] = s
+	}
+
+	if fetched {
+		if err := a.Cache.Save(); err != nil {
+			log.WithField("err", err).Error("Could not save cache")
+		}
+	}
+	return ret
+}
+
+// FilterCompletion returns games from the list in which every client owning
+// the game unlocked at least min percent of the achievements
+// Games without achievements are left out.
+func (a *Aggregator) FilterCompletion(games objects.JSONGameList, min float64) objects.JSONGameList {
+	return a.FilterCompletionContext(context.Background(), games, min)
+}
+
+// FilterCompletionContext is like FilterCompletion, the requests are cancelled
+// once ctx is done
+func (a *Aggregator) FilterCompletionContext(ctx context.Context, games objects.JSONGameList, min float64) objects.JSONGameList {
+	ret := make(objects.JSONGameList, 0, len(games))
+	for _, g := range games {
+		progress := a.AchievementsContext(ctx, g.AppID)
+		if len(progress) < 1 {
+			continue
+		}
+		ok := true
+		for _, s := range progress {
+			if s.Completion() < min {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			ret = append(ret, g)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,109 @@
+package aggregator
+
+import (
+	"testing"
+
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+func TestAchievementsCachesUnavailableStats(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddProfile(&steamtest.Profile{
+		SteamID64: 76561197960287930,
+		Name:      "owner",
+		Games:     []steamtest.Game{{AppID: 10, Name: "Counter-Strike", HoursTotal: 1}},
+	})
+	a := newTestAggregator(t, s, "76561197960287930")
+
+	for i := 0; i < 2; i++ {
+		if progress := a.Achievements(10); len(progress) != 0 {
+			t.Fatalf("run %d: got progress %v for a game without stats", i, progress)
+		}
+	}
+	if n := countRequests(s, "/stats/10/"); n != 1 {
+		t.Errorf("stats were requested %d times, want 1", n)
+	}
+
+	stats := a.Clients["76561197960287930"].Profile.Achievements[10]
+	if (stats == nil) || !stats.Unavailable || stats.Updated.IsZero() {
+		t.Errorf("unavailable stats weren't recorded in the profile: %+v", stats)
+	}
+}
+
+func TestAchievementsCompare(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	achievements := func(unlocked ...bool) []steamtest.Achievement {
+		ret := make([]steamtest.Achievement, 0, len(unlocked))
+		for i, u := range unlocked {
+			ret = append(ret, steamtest.Achievement{APIName: string(rune('A' + i)), Name: "Achievement", Unlocked: u})
+		}
+		return ret
+	}
+	s.AddProfile(&steamtest.Profile{
+		SteamID64: 76561197960287930,
+		Name:      "completionist",
+		Games: []steamtest.Game{
+			{AppID: 10, Name: "Portal", HoursTotal: 5, Achievements: achievements(true, true, true, true)},
+			{AppID: 20, Name: "Half-Life", HoursTotal: 5},
+		},
+	})
+	s.AddProfile(&steamtest.Profile{
+		SteamID64: 76561197960287931,
+		Name:      "casual",
+		Games: []steamtest.Game{
+			{AppID: 10, Name: "Portal", HoursTotal: 1, Achievements: achievements(true, false, false, false)},
+		},
+	})
+	a := newTestAggregator(t, s, "76561197960287930", "76561197960287931")
+
+	progress := a.Achievements(10)
+	want := map[string]struct {
+		unlocked   int
+		completion float64
+	}{
+		"76561197960287930": {4, 100},
+		"76561197960287931": {1, 25},
+	}
+	if len(progress) != len(want) {
+		t.Fatalf("Achievements() = %v, want progress of both clients", progress)
+	}
+	for id, w := range want {
+		p := progress[id]
+		if (p == nil) || (p.Unlocked() != w.unlocked) || (p.Completion() != w.completion) || (p.GameName != "Portal") {
+			t.Errorf("%s: progress = %+v, want %d unlocked", id, p, w.unlocked)
+		}
+	}
+
+	// The progress is cached with the profiles
+	before := countRequests(s, "/stats/")
+	a.Achievements(10)
+	if n := countRequests(s, "/stats/") - before; n != 0 {
+		t.Errorf("cached achievements were requested %d more times", n)
+	}
+	a.Cache.Lock() // Find purges expired profiles
+	cached, _ := a.Cache.Profiles.Find("76561197960287931")
+	a.Cache.Unlock()
+	if (cached == nil) || (cached.Achievements[10] == nil) || (cached.Achievements[10].Unlocked() != 1) {
+		t.Errorf("cached profile achievements = %+v", cached)
+	}
+
+	games := objects.JSONGameList{{AppID: 10}, {AppID: 20}}
+	tests := []struct {
+		min  float64
+		want int // Matching games
+	}{
+		{0, 1}, // Half-Life has no stats
+		{25, 1},
+		{50, 0},
+	}
+	for _, tt := range tests {
+		if got := a.FilterCompletion(games, tt.min); len(got) != tt.want {
+			t.Errorf("FilterCompletion(%g) = %d games, want %d", tt.min, len(got), tt.want)
+		}
+	}
+}
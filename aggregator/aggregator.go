@@ -2,10 +2,15 @@
 package aggregator
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"gitlab.com/vultour/steamcli/api/profile"
 	"gitlab.com/vultour/steamcli/cache"
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -14,45 +19,129 @@ import (
 type Aggregator struct {
 	Clients ClientMap
 	Cache   *cache.Cache
+
+	// OnProgress is called whenever a game was processed by UpdateGames,
+	// UpdateGameTags, or UpdateGameReviews (and their variants)
+	// Calls are never concurrent but are made from worker goroutines, so the
+	// function shouldn't block for long.
+	OnProgress ProgressFunc
+
+	mu      sync.Mutex // Guards Clients while clients are added concurrently
+	flights flightGroup
 }
 
+// ErrClientExists is returned when adding a client that is already present
+var ErrClientExists = steamerr.New(steamerr.ErrInvalid, "client is already present")
+
 // ClientMap is a map between a user's steam ID and their API Client
 type ClientMap map[string]*profile.Client
 
-// New returns an initialized Aggregator struct
-func New() *Aggregator {
+// New returns an initialized Aggregator struct using the cache at
+// cache.FileLocation, see cache.New for the errors returned
+func New() (*Aggregator, error) {
+	c, err := cache.New()
+	if err != nil {
+		return nil, err
+	}
 	a := &Aggregator{
 		Clients: make(ClientMap),
-		Cache:   cache.New(),
+		Cache:   c,
 	}
-	return a
+	return a, nil
 }
 
 // AddClient initializes and adds a new client to the Aggregator
 func (a *Aggregator) AddClient(id string) error {
-	var newClient *profile.Client
-	var err error
+	return a.AddClientContext(context.Background(), id)
+}
 
-	if _, ok := a.Clients[id]; ok {
-		return fmt.Errorf("The client is already present: '%s'", id)
+// AddClientContext is like AddClient, the requests are cancelled once ctx is
+// done
+func (a *Aggregator) AddClientContext(ctx context.Context, id string) error {
+	if a.hasClient(id) {
+		return fmt.Errorf("%w: '%s'", ErrClientExists, id)
 	}
 
-	if p, found := a.Cache.Profiles.Find(id); found {
-		log.WithField("name", p.SteamID).Debug("Reusing cached client profile")
-		newClient = profile.NewClientPre(p)
-	} else {
-		log.Debug("Creating new client")
-		newClient, err = profile.NewClient(id)
-		if err != nil {
-			return err
-		}
-		a.Cache.Profiles.Add(&newClient.Profile)
+	newClient, err := a.client(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	a.setClient(id, newClient)
+	return nil
+}
+
+// addPublicClient adds a new client unless its profile isn't public
+// Returns true if the client was added.
+func (a *Aggregator) addPublicClient(ctx context.Context, id string) (bool, error) {
+	if a.hasClient(id) {
+		return false, nil
+	}
+
+	newClient, err := a.client(ctx, id)
+	if errors.Is(err, profile.ErrProfilePrivate) || errors.Is(err, profile.ErrGamesPrivate) {
+		log.WithFields(log.Fields{
+			"id":  id,
+			"err": err,
+		}).Info("Skipping private profile")
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if newClient.Profile.PrivacyState != objects.VisibilityPublic {
+		log.WithField("id", id).Info("Skipping private profile")
+		return false, nil
 	}
 
+	a.setClient(id, newClient)
+	return true, nil
+}
+
+func (a *Aggregator) hasClient(id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.Clients[id]
+	return ok
+}
+
+func (a *Aggregator) setClient(id string, c *profile.Client) {
 	log.WithFields(log.Fields{
 		"id":   id,
-		"name": newClient.Profile.SteamID,
+		"name": c.Profile.SteamID,
 	}).Info("New client:")
-	a.Clients[id] = newClient
-	return nil
+	a.mu.Lock()
+	a.Clients[id] = c
+	a.mu.Unlock()
+}
+
+// client returns a client for the ID, using the cached profile if possible
+func (a *Aggregator) client(ctx context.Context, id string) (*profile.Client, error) {
+	if p, found := a.cachedProfile(id); found {
+		log.WithField("name", p.SteamID).Debug("Reusing cached client profile")
+		return profile.NewClientPre(p), nil
+	}
+
+	log.Debug("Creating new client")
+	newClient, err := profile.NewClientContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	a.cacheProfile(&newClient.Profile)
+	return newClient, nil
+}
+
+// cachedProfile returns the cached profile for the ID
+// The cache lock is taken for writing as expired profiles are purged.
+func (a *Aggregator) cachedProfile(id string) (*objects.XMLProfile, bool) {
+	a.Cache.Lock()
+	defer a.Cache.Unlock()
+	return a.Cache.Profiles.Find(id)
+}
+
+// cacheProfile adds or replaces the profile in the cache
+func (a *Aggregator) cacheProfile(p *objects.XMLProfile) {
+	a.Cache.Lock()
+	defer a.Cache.Unlock()
+	a.Cache.Profiles.Add(p)
 }
@@ -0,0 +1,38 @@
+package aggregator
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/vultour/steamcli/cache"
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+// newTestAggregator returns an Aggregator using a new cache file in a
+// temporary directory, with clients for the IDs served by s
+func newTestAggregator(t *testing.T, s *steamtest.Server, ids ...string) *Aggregator {
+	t.Helper()
+	cache.FileLocation = filepath.Join(t.TempDir(), "cache.json")
+	a, err := New()
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	for _, id := range ids {
+		if err := a.AddClient(id); err != nil {
+			t.Fatalf("AddClient(%s) = %v", id, err)
+		}
+	}
+	return a
+}
+
+// countRequests returns how many requests to s contained the substring
+func countRequests(s *steamtest.Server, substr string) int {
+	n := 0
+	for _, r := range s.Requests() {
+		if strings.Contains(r, substr) {
+			n++
+		}
+	}
+	return n
+}
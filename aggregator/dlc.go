@@ -0,0 +1,60 @@
+package aggregator
+
+import (
+	"gitlab.com/vultour/steamcli/objects"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GameGroup is a base game together with the selected DLC belonging to it
+type GameGroup struct {
+	Game *objects.JSONGame
+	DLC  objects.JSONGameList
+}
+
+// Group folds the DLC in the list under their base games
+// DLC whose base game is not in the list is returned as its own group. The
+// order of the list is preserved.
+func (a *Aggregator) Group(games objects.JSONGameList) []*GameGroup {
+	groups := make(map[int]*GameGroup)
+	ret := make([]*GameGroup, 0, len(games))
+	for _, g := range games {
+		if !g.IsDLC() {
+			grp := &GameGroup{Game: g, DLC: make(objects.JSONGameList, 0)}
+			groups[g.AppID] = grp
+			ret = append(ret, grp)
+		}
+	}
+
+	for _, g := range games {
+		if !g.IsDLC() {
+			continue
+		}
+		if grp, ok := groups[g.FullGameID()]; ok {
+			grp.DLC = append(grp.DLC, g)
+			continue
+		}
+		log.WithFields(log.Fields{
+			"id":   g.AppID,
+			"base": g.FullGameID(),
+		}).Debug("Base game of DLC not selected")
+		ret = append(ret, &GameGroup{Game: g, DLC: make(objects.JSONGameList, 0)})
+	}
+	return ret
+}
+
+// DLCOwned returns how many of the game's DLC every client owns
+// The returned map is keyed by the same ID as the clients.
+func (a *Aggregator) DLCOwned(g *objects.JSONGame) map[string]int {
+	ret := make(map[string]int)
+	for id, c := range a.Clients {
+		n := 0
+		for _, dlc := range g.DLC {
+			if _, owned := c.Profile.Games.Contains(dlc); owned {
+				n++
+			}
+		}
+		ret[id] = n
+	}
+	return ret
+}
@@ -0,0 +1,83 @@
+package aggregator
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"gitlab.com/vultour/steamcli/api/profile"
+	"gitlab.com/vultour/steamcli/objects"
+)
+
+// newDLC returns a DLC of the base game
+func newDLC(appid, base int) *objects.JSONGame {
+	g := &objects.JSONGame{AppID: appid, Type: "dlc"}
+	g.FullGame.AppID = strconv.Itoa(base)
+	return g
+}
+
+// newOwner returns a client owning the apps
+func newOwner(name string, appids ...int) *profile.Client {
+	c := &profile.Client{}
+	c.Profile.SteamID = name
+	c.Profile.Games = make(objects.XMLGameMap)
+	for _, id := range appids {
+		c.Profile.Games[id] = &objects.XMLProfileGame{AppID: id}
+	}
+	return c
+}
+
+func TestGroup(t *testing.T) {
+	base := &objects.JSONGame{AppID: 1, Type: "game", DLC: []int{2, 3}}
+	other := &objects.JSONGame{AppID: 10, Type: "game"}
+	orphan := newDLC(20, 99) // Base game not in the list
+	games := objects.JSONGameList{newDLC(3, 1), other, base, orphan, newDLC(2, 1)}
+
+	groups := (&Aggregator{}).Group(games)
+	got := make(map[int][]int)
+	order := make([]int, 0, len(groups))
+	for _, grp := range groups {
+		order = append(order, grp.Game.AppID)
+		got[grp.Game.AppID] = make([]int, 0, len(grp.DLC))
+		for _, dlc := range grp.DLC {
+			got[grp.Game.AppID] = append(got[grp.Game.AppID], dlc.AppID)
+		}
+	}
+	if want := []int{10, 1, 20}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Group() order = %v, want %v", order, want)
+	}
+	want := map[int][]int{1: {3, 2}, 10: {}, 20: {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Group() = %v, want %v", got, want)
+	}
+}
+
+func TestDLCOwned(t *testing.T) {
+	base := &objects.JSONGame{AppID: 1, Type: "game", DLC: []int{2, 3, 4}}
+	tests := []struct {
+		name  string
+		owned []int
+		n     int
+	}{
+		{"none", []int{1}, 0},
+		{"some", []int{1, 2, 4}, 2},
+		{"all", []int{1, 2, 3, 4}, 3},
+		{"dlc without the game", []int{3}, 1},
+	}
+	a := &Aggregator{Clients: make(ClientMap)}
+	want := make(map[string]int)
+	for _, tt := range tests {
+		a.Clients[tt.name] = newOwner(tt.name, tt.owned...)
+		want[tt.name] = tt.n
+	}
+	if got := a.DLCOwned(base); !reflect.DeepEqual(got, want) {
+		t.Errorf("DLCOwned() = %v, want %v", got, want)
+	}
+
+	// Games without DLC count nothing for everyone
+	for id, n := range a.DLCOwned(&objects.JSONGame{AppID: 10}) {
+		if n != 0 {
+			t.Errorf("DLCOwned() of a game without DLC = %d for %s", n, id)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package aggregator
+
+import (
+	"context"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxFriends is the default limit of friends added by AddFriends
+var MaxFriends = 50
+
+// AddFriends adds the specified profile and up to max of its friends as
+// clients to the Aggregator
+// Friends with private profiles or game lists are skipped. Returns the number
+// of added friends.
+func (a *Aggregator) AddFriends(id string, max int) (int, error) {
+	return a.AddFriendsContext(context.Background(), id, max)
+}
+
+// AddFriendsContext is like AddFriends, the requests are cancelled once ctx is
+// done
+func (a *Aggregator) AddFriendsContext(ctx context.Context, id string, max int) (int, error) {
+	if _, ok := a.Clients[id]; !ok {
+		if err := a.AddClientContext(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+	c := a.Clients[id]
+
+	if c.Profile.Friends == nil {
+		if err := c.GetFriendsContext(ctx); err != nil {
+			return 0, err
+		}
+		a.cacheProfile(&c.Profile)
+	}
+	log.WithFields(log.Fields{
+		"id":      id,
+		"friends": len(c.Profile.Friends),
+	}).Debug("Adding friends")
+
+	n := 0
+	for _, friend := range c.Profile.Friends {
+		if (max > 0) && (n >= max) {
+			log.WithField("max", max).Info("Reached maximum number of friends")
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		fid := strconv.FormatInt(friend, 10)
+		added, err := a.addPublicClient(ctx, fid)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id":  fid,
+				"err": err,
+			}).Info("Skipping friend")
+			continue
+		}
+		if added {
+			n++
+		}
+	}
+
+	if err := a.Cache.Save(); err != nil {
+		return n, err
+	}
+	return n, ctx.Err()
+}
@@ -1,17 +1,21 @@
 package aggregator
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/http/cookiejar"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
-	"time"
 
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/api/profile"
 	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/html"
@@ -20,24 +24,59 @@ import (
 
 type gameMatcher []*map[int]struct{}
 
-// GameEndpoint is the Steam Store's endpoint for one or more games
-const GameEndpoint = "https://store.steampowered.com/api/appdetails/?appids="
+// ErrMissingGame is returned when the store response lacks a requested game
+var ErrMissingGame = steamerr.New(steamerr.ErrDecode, "game missing from the store response")
 
-// GameEndpointHTML is the human-readable store endpoint for a single game
-// The 'API' endpoint does not return tags, so we need to parse the HTML version
-const GameEndpointHTML = "https://store.steampowered.com/app/"
+// ErrNoStorePage is returned when the store doesn't have a page for a game,
+// e.g. because it was delisted
+var ErrNoStorePage = steamerr.New(steamerr.ErrNotFound, "returned page did not pass validity check")
 
 // ParallelUpdates defines how many games will be fetched at one time from store
 var ParallelUpdates = 1
 
+// Country is the store region (ISO 3166 country code) used for prices and
+// availability, the store picks one based on the request origin if left empty
+var Country = ""
+
+// Language is the store language used for names and descriptions, the store
+// picks one based on the request origin if left empty
+var Language = ""
+
 // Select returns games across all profiles matching the specified criteria
 func (a *Aggregator) Select(tags []string, common, and, invalid bool) objects.JSONGameList {
+	return a.selectFrom(
+		ownedGames,
+		tags, common, 0, and, invalid,
+	)
+}
+
+// SelectPopular returns games owned by at least minOwners profiles matching
+// the specified criteria
+func (a *Aggregator) SelectPopular(tags []string, minOwners int, and, invalid bool) objects.JSONGameList {
+	return a.selectFrom(
+		ownedGames,
+		tags, false, minOwners, and, invalid,
+	)
+}
+
+// ownedGames returns the App IDs of all games the client owns
+func ownedGames(c *profile.Client) []int {
+	ret := make([]int, 0, len(c.Profile.Games))
+	for id := range c.Profile.Games {
+		ret = append(ret, id)
+	}
+	return ret
+}
+
+// selectFrom returns cached games matching the criteria out of the App IDs
+// provided by source for every client, minOwners is ignored if common is set
+func (a *Aggregator) selectFrom(source func(c *profile.Client) []int, tags []string, common bool, minOwners int, and, invalid bool) objects.JSONGameList {
 	matcher := make(gameMatcher, 0, 3)
 	for _, c := range a.Clients {
 		games := make(map[int]struct{})
-		for _, g := range c.Profile.Games {
-			log.WithField("id", g.AppID).Debug("Adding game to matcher")
-			games[g.AppID] = struct{}{}
+		for _, id := range source(c) {
+			log.WithField("id", id).Debug("Adding game to matcher")
+			games[id] = struct{}{}
 		}
 		matcher = append(matcher, &games)
 		log.WithField("size", len(games)).Debug("Created matcher section")
@@ -45,6 +84,8 @@ func (a *Aggregator) Select(tags []string, common, and, invalid bool) objects.JS
 	var wantedIDs []int
 	if common {
 		wantedIDs = matcher.Common()
+	} else if minOwners > 1 {
+		wantedIDs = matcher.AtLeast(minOwners)
 	} else {
 		wantedIDs = matcher.All()
 	}
@@ -53,192 +94,336 @@ func (a *Aggregator) Select(tags []string, common, and, invalid bool) objects.JS
 }
 
 // UpdateGameCache updates the aggregator game cache.
-// This fetches the details of every game owned across all clients and stores
-// it in the cache.
-func (a *Aggregator) UpdateGameCache() error {
-	gameIDs := make(map[int]struct{})
+// This fetches the details of every game owned or wishlisted across all
+// clients and stores it in the cache, along with any games left pending by
+// an interrupted update.
+func (a *Aggregator) UpdateGameCache() (*UpdateReport, error) {
+	return a.UpdateGameCacheContext(context.Background())
+}
+
+// UpdateGameCacheContext is like UpdateGameCache, see UpdateGamesContext
+func (a *Aggregator) UpdateGameCacheContext(ctx context.Context) (*UpdateReport, error) {
+	ids := make([]int, 0, 64)
 	for _, c := range a.Clients {
 		for id := range c.Profile.Games {
-			if _, cached := a.Cache.Games.Get(id); !cached {
-				gameIDs[id] = struct{}{}
-			}
+			ids = append(ids, id)
+		}
+		for id := range c.Profile.Wishlist {
+			ids = append(ids, id)
 		}
 	}
+	return a.UpdateGamesContext(ctx, ids)
+}
+
+// ResumeGames fetches the games left pending by an interrupted update
+func (a *Aggregator) ResumeGames() (*UpdateReport, error) {
+	return a.UpdateGames(nil)
+}
+
+// ResumeGamesContext is like ResumeGames, see UpdateGamesContext
+func (a *Aggregator) ResumeGamesContext(ctx context.Context) (*UpdateReport, error) {
+	return a.UpdateGamesContext(ctx, nil)
+}
+
+// UpdateGames fetches the details of the specified games and the games left
+// pending by an interrupted update unless they're already cached, and stores
+// them in the cache.
+// Up to Workers requests are made at the same time, each fetching
+// ParallelUpdates games. The games are queued in the cache before fetching
+// and the cache is saved every now and then (see cache.Checkpoint), so an
+// interrupted update can be resumed. A failed request doesn't stop the update,
+// its games stay queued and are counted as failed in the report.
+func (a *Aggregator) UpdateGames(ids []int) (*UpdateReport, error) {
+	return a.UpdateGamesContext(context.Background(), ids)
+}
+
+// UpdateGamesContext is like UpdateGames but stops once ctx is done
+// The requests in flight are cancelled, their games stay queued. The cache is
+// saved and the context's error is returned along with the report.
+func (a *Aggregator) UpdateGamesContext(ctx context.Context, ids []int) (*UpdateReport, error) {
+	report := &UpdateReport{}
+	gameIDs := make(map[int]struct{})
+	seen := make(map[int]struct{})
+	a.Cache.RLock()
+	for _, id := range append(append([]int(nil), ids...), a.Cache.Pending...) {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		if g, cached := a.Cache.Games.Get(id); !cached {
+			gameIDs[id] = struct{}{}
+		} else if g.Outdated() {
+			log.WithField("id", id).Debug("Cached game is outdated")
+			gameIDs[id] = struct{}{}
+		} else if (Country != "") && !g.Invalid && !g.HasRegion(Country) {
+			log.WithFields(log.Fields{
+				"id":      id,
+				"country": Country,
+			}).Debug("Game not cached for region")
+			gameIDs[id] = struct{}{}
+		} else {
+			report.Skipped++
+		}
+	}
+	stale := make([]int, 0)
+	for _, id := range a.Cache.Pending {
+		if _, ok := gameIDs[id]; !ok {
+			stale = append(stale, id)
+		}
+	}
+	a.Cache.RUnlock()
 	log.WithField("n", len(gameIDs)).Debug("Accumulated game IDs")
+	if (len(gameIDs) < 1) && (len(stale) < 1) {
+		return report, nil
+	}
 
-	c := http.Client{Timeout: time.Second * 10}
-	for len(gameIDs) > 0 {
-		nextIDs := make([]string, 0, ParallelUpdates)
-		i := 0
-		for g := range gameIDs {
-			nextIDs = append(nextIDs, strconv.Itoa(g))
-			i++
-			if i >= ParallelUpdates {
-				break
-			}
+	queue := make([]int, 0, len(gameIDs))
+	for g := range gameIDs {
+		queue = append(queue, g)
+	}
+	sort.Ints(queue)
+	for _, id := range stale {
+		a.Cache.RemovePending(id)
+	}
+	a.Cache.AddPending(queue)
+	if err := a.Cache.Save(); err != nil {
+		return report, err
+	}
+
+	batches := make([][]string, 0, len(queue))
+	for _, g := range queue {
+		if (len(batches) < 1) || (len(batches[len(batches)-1]) >= ParallelUpdates) {
+			batches = append(batches, make([]string, 0, ParallelUpdates))
 		}
-		log.WithField("ids", strings.Join(nextIDs, ",")).Debug("Fetching games")
+		batches[len(batches)-1] = append(batches[len(batches)-1], strconv.Itoa(g))
+	}
 
-		url := fmt.Sprintf("%s%s", GameEndpoint, strings.Join(nextIDs, ","))
-		log.Debugf("Built URL: %s", url)
-		r, err := c.Get(url)
+	c := endpoints.NewHTTPClient(storeTimeout)
+	progress := newProgress(a.OnProgress, "games", len(queue))
+	err := runWorkers(ctx, Workers, len(batches), func(i int) error {
+		key := "details:" + strings.Join(batches[i], ",")
+		invalid, err := a.flights.Do(key, func() (interface{}, error) {
+			return a.fetchGames(ctx, c, batches[i])
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		last, _ := strconv.Atoi(batches[i][len(batches[i])-1])
 		if err != nil {
-			return fmt.Errorf("could not retrieve data from the store: %s", err)
+			log.WithFields(log.Fields{
+				"err": err,
+				"ids": strings.Join(batches[i], ","),
+			}).Error("Failed fetching games")
+			report.add(0, 0, len(batches[i]), err)
+			progress.step(len(batches[i]), last, a.gameName(last), true)
+			return nil
+		}
+		for _, id := range batches[i] {
+			appid, _ := strconv.Atoi(id)
+			a.Cache.RemovePending(appid)
 		}
-		defer r.Body.Close()
+		report.add(len(batches[i]), invalid.(int), 0, nil)
+		progress.step(len(batches[i]), last, a.gameName(last), false)
+		return a.Cache.Checkpoint()
+	})
+	if serr := a.Cache.Save(); serr != nil {
+		return report, serr
+	}
+	return report, err
+}
 
-		log.WithField("status", r.StatusCode).Debug("Got response")
+// gameName returns the name of the game from the cache or the cached profiles,
+// or an empty string if it's unknown
+func (a *Aggregator) gameName(appid int) string {
+	a.Cache.RLock()
+	defer a.Cache.RUnlock()
+	if g, cached := a.Cache.Games.Get(appid); cached && (g.Name != "") {
+		return g.Name
+	}
+	name, _ := a.Cache.Profiles.FindGame(appid)
+	return name
+}
 
-		b, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			return fmt.Errorf("couldn't read response body: %s", err)
-		}
+// fetchGames fetches the details of the games in one request and stores them
+// in the cache
+// Returns how many of the games were invalid.
+func (a *Aggregator) fetchGames(ctx context.Context, c *http.Client, nextIDs []string) (int, error) {
+	log.WithField("ids", strings.Join(nextIDs, ",")).Debug("Fetching games")
 
-		if strings.TrimSpace(string(b)) == "null" {
-			return fmt.Errorf("rate limit exceeded or unsupported parallelisation number used")
-		}
+	url := fmt.Sprintf(
+		"%s/?appids=%s%s",
+		storeURL(endpoints.AppDetails), strings.Join(nextIDs, ","), regionParams("&"),
+	)
+	r, err := storeGet(ctx, c, url)
+	if err != nil {
+		return 0, steamerr.Wrap(steamerr.ErrNetwork, "could not retrieve data from the store", err)
+	}
+	defer r.Body.Close()
+
+	log.WithField("status", r.StatusCode).Debug("Got response")
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return 0, steamerr.Wrap(steamerr.ErrNetwork, "couldn't read response body", err)
+	}
+
+	if strings.TrimSpace(string(b)) == "null" {
+		return 0, steamerr.New(steamerr.ErrRateLimited, "store kept returning null: rate limit exceeded or unsupported parallelisation number used")
+	}
 
-		// Anonymous struct to deal with the API uglyness
-		obj := map[string]*struct {
-			Success bool              `json:"success"`
-			Data    *objects.JSONGame `json:"data"`
-		}{}
-		err = json.Unmarshal(b, &obj)
+	// Anonymous struct to deal with the API uglyness
+	obj := map[string]*struct {
+		Success bool              `json:"success"`
+		Data    *objects.JSONGame `json:"data"`
+	}{}
+	err = json.Unmarshal(b, &obj)
+	if err != nil {
+		return 0, steamerr.Wrap(steamerr.ErrDecode, "couldn't decode json", err)
+	}
+
+	invalid := 0
+	for _, v := range nextIDs {
+		vi, err := strconv.Atoi(v)
 		if err != nil {
-			return fmt.Errorf("couldn't decode json: %s", err)
+			return 0, steamerr.Wrap(steamerr.ErrInvalid, "couldn't convert AppID", err)
 		}
 
-		for _, v := range nextIDs {
-			vi, err := strconv.Atoi(v)
-			if err != nil {
-				return fmt.Errorf("couldn't convert AppID: %s", err)
-			}
-
-			if vg, exists := obj[v]; exists {
-				if !obj[v].Success {
-					// Set as invalid and backfill from profile
-					log.WithField("id", v).Warning("received invalid response from store")
-					pGame, ex := a.Cache.Profiles.FindGame(vi)
-					if !ex {
-						log.WithField("id", vi).Error("Could not backfill game from profile")
-					}
-					log.WithField("name", pGame).Debug("Backfilling game name")
-					obj[v].Data = &objects.JSONGame{
-						AppID:   vi,
-						Invalid: true,
-						Name:    pGame,
-					}
-				}
+		vg, exists := obj[v]
+		if !exists || (vg == nil) {
+			log.WithFields(log.Fields{
+				"id":       v,
+				"response": string(b),
+			}).Debug("Didn't find game in response")
+			return invalid, fmt.Errorf("%w: %s", ErrMissingGame, v)
+		}
 
-				// Add game to cache
+		if !vg.Success || (vg.Data == nil) {
+			log.WithField("id", v).Warning("received invalid response from store")
+			invalid++
+			// A game cached from another region isn't sold in this one
+			if a.Cache.StoreUnavailable(vi, Country) {
 				log.WithFields(log.Fields{
-					"id_s": vi,
-					"id_i": vg.Data.AppID,
-				}).Debug("Adding game to cache")
-				vg.Data.Complete()
-				a.Cache.Games.Add(vg.Data.AppID, vg.Data)
-
-				// Add a duplicate entry if received mismatch to avoid loop
-				if obj[v].Data.AppID != vi {
-					log.WithFields(log.Fields{
-						"id_requested": vi,
-						"id_received":  obj[v].Data.AppID,
-					}).Warn("AppID mismatch")
-					a.Cache.Games.Add(vi, vg.Data)
-					delete(gameIDs, vi)
-				}
+					"id":      vi,
+					"country": Country,
+				}).Debug("Game not available in region")
+				continue
+			}
 
-				delete(gameIDs, vg.Data.AppID)
-			} else {
-				log.WithFields(log.Fields{
-					"id":       v,
-					"response": string(b),
-				}).Panic("Didn't find game in response")
+			// Set as invalid and backfill from profile
+			a.Cache.RLock()
+			pGame, ex := a.Cache.Profiles.FindGame(vi)
+			a.Cache.RUnlock()
+			if !ex {
+				log.WithField("id", vi).Error("Could not backfill game from profile")
+			}
+			log.WithField("name", pGame).Debug("Backfilling game name")
+			vg.Data = &objects.JSONGame{
+				AppID:   vi,
+				Invalid: true,
+				Name:    pGame,
 			}
 		}
-		if err := a.Cache.Save(); err != nil {
-			return err
-		}
-		time.Sleep(time.Millisecond * 600)
-	}
 
-	if err := a.Cache.Save(); err != nil {
-		return err
+		// Add game to cache
+		log.WithFields(log.Fields{
+			"id_s": vi,
+			"id_i": vg.Data.AppID,
+		}).Debug("Adding game to cache")
+		vg.Data.Complete(Country)
+		a.Cache.StoreGame(vg.Data.AppID, vg.Data)
+
+		// Add a duplicate entry if received mismatch to avoid loop
+		if vg.Data.AppID != vi {
+			log.WithFields(log.Fields{
+				"id_requested": vi,
+				"id_received":  vg.Data.AppID,
+			}).Warn("AppID mismatch")
+			a.Cache.StoreGame(vi, vg.Data)
+		}
 	}
-	return nil
+	return invalid, nil
 }
 
 // UpdateGameTags fetches Game tags for all games that are eligible
-func (a *Aggregator) UpdateGameTags() error {
+// Up to Workers pages are fetched at the same time. Games without a store page
+// get empty tags and are counted as invalid in the report, games whose page
+// couldn't be fetched are counted as failed and retried by the next update.
+func (a *Aggregator) UpdateGameTags() (*UpdateReport, error) {
+	return a.UpdateGameTagsContext(context.Background())
+}
+
+// UpdateGameTagsContext is like UpdateGameTags, the requests are cancelled
+// once ctx is done
+func (a *Aggregator) UpdateGameTagsContext(ctx context.Context) (*UpdateReport, error) {
 	log.Debug("Updating tags")
-	c := &http.Client{Timeout: time.Second * 10}
-	jar, err := cookiejar.New(nil)
+	report := &UpdateReport{}
+	c, err := newStoreClient()
 	if err != nil {
-		return fmt.Errorf("could not create cookiejar: %s", err)
+		return report, err
 	}
 
-	c.Jar = jar
-	storeURL, err := url.Parse("https://store.steampowered.com")
-	if err != nil {
-		return fmt.Errorf("Could not parse store URL: %s", err)
-	}
-	c.Jar.SetCookies(
-		storeURL,
-		[]*http.Cookie{
-			&http.Cookie{
-				Name:    "birthtime",
-				Expires: time.Now().Add(time.Hour * 12),
-				Domain:  "store.steampowered.com",
-				Path:    "/",
-				Value:   "156729601",
-			},
-			&http.Cookie{
-				Name:    "lastagecheckage",
-				Expires: time.Now().Add(time.Hour * 12),
-				Domain:  "store.steampowered.com",
-				Path:    "/",
-				Value:   "1-0-1987",
-			},
-			&http.Cookie{
-				Name:    "wants_mature_content",
-				Expires: time.Now().Add(time.Hour * 12),
-				Domain:  "store.steampowered.com",
-				Path:    "/",
-				Value:   "1",
-			},
-		},
-	)
-
+	ids := make([]int, 0, 16)
+	a.Cache.RLock()
 	for i, g := range a.Cache.Games {
 		if g.Tags == nil {
-			t, err := fetchTags(c, i)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"err": err,
-					"id":  i,
-				}).Error("Failed fetching tags")
-			}
+			ids = append(ids, i)
+		}
+	}
+	a.Cache.RUnlock()
+
+	progress := newProgress(a.OnProgress, "tags", len(ids))
+	err = runWorkers(ctx, Workers, len(ids), func(n int) error {
+		i := ids[n]
+		t, err := a.flights.Do("tags:"+strconv.Itoa(i), func() (interface{}, error) {
+			return fetchTags(ctx, c, i)
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tags, _ := t.([]string)
+		switch {
+		case errors.Is(err, ErrNoStorePage):
+			log.WithField("id", i).Warning("Game has no store page")
+			tags = []string{}
+			report.add(1, 1, 0, nil)
+		case err != nil:
 			log.WithFields(log.Fields{
-				"id":   i,
-				"tags": t,
-			}).Debug("Retrieved tags")
-			g.Tags = t
-			if err := a.Cache.Save(); err != nil {
-				return err
-			}
-			time.Sleep(time.Second)
+				"err": err,
+				"id":  i,
+			}).Error("Failed fetching tags")
+			report.add(0, 0, 1, err)
+			progress.step(1, i, a.gameName(i), true)
+			return nil
+		default:
+			report.add(1, 0, 0, nil)
+		}
+		progress.step(1, i, a.gameName(i), false)
+		log.WithFields(log.Fields{
+			"id":   i,
+			"tags": tags,
+		}).Debug("Retrieved tags")
+
+		a.Cache.Lock()
+		if g, cached := a.Cache.Games.Get(i); cached {
+			g.Tags = tags
+			a.Cache.Changed()
 		}
+		a.Cache.Unlock()
+		return a.Cache.Checkpoint()
+	})
+	if serr := a.Cache.Save(); serr != nil {
+		return report, serr
 	}
-	return nil
+	return report, err
 }
 
-func fetchTags(httpClient *http.Client, appid int) ([]string, error) {
+func fetchTags(ctx context.Context, httpClient *http.Client, appid int) ([]string, error) {
 	log.WithField("appid", appid).Debug("Retrieving tags")
-	u := fmt.Sprintf("%s%d", GameEndpointHTML, appid)
-	log.Debugf("Built URL: %s", u)
-	r, err := httpClient.Get(u)
+	// The API endpoint does not return tags, so we need to parse the HTML version
+	u := fmt.Sprintf("%s/%d%s", storeURL(endpoints.App), appid, regionParams("/?"))
+	r, err := storeGet(ctx, httpClient, u)
 	if err != nil {
-		return []string{}, fmt.Errorf("could not retrieve data from the store: %s", err)
+		return nil, steamerr.Wrap(steamerr.ErrNetwork, "could not retrieve data from the store", err)
 	}
 	defer r.Body.Close()
 
@@ -246,7 +431,7 @@ func fetchTags(httpClient *http.Client, appid int) ([]string, error) {
 
 	doc, err := html.Parse(r.Body)
 	if err != nil {
-		return []string{}, fmt.Errorf("could not parse HTML: %s", err)
+		return nil, steamerr.Wrap(steamerr.ErrDecode, "could not parse HTML", err)
 	}
 
 	if !tagReturnSuccess(doc, appid) {
@@ -256,7 +441,7 @@ func fetchTags(httpClient *http.Client, appid int) ([]string, error) {
 			log.WithField("err", err).Error("Could not render page back into HTML")
 		}
 		log.Debugf("Validity check failed, content: %s", s.String())
-		return []string{}, fmt.Errorf("returned page did not pass validity check")
+		return nil, ErrNoStorePage
 	}
 
 	tags := findTags(doc)
@@ -267,7 +452,7 @@ func fetchTags(httpClient *http.Client, appid int) ([]string, error) {
 			log.WithField("err", err).Error("Could not render page back into HTML")
 		}
 		log.Debugf("Content: %s", s.String())
-		uuu, _ := url.Parse("https://store.steampowered.com")
+		uuu, _ := url.Parse(endpoints.Config.Store)
 		for _, c := range httpClient.Jar.Cookies(uuu) {
 			log.Debugf("Cookie: %#v", c)
 		}
@@ -277,6 +462,22 @@ func fetchTags(httpClient *http.Client, appid int) ([]string, error) {
 	return tags, nil
 }
 
+// regionParams returns the query parameters selecting the store region and
+// language, prefixed by sep; returns an empty string if neither is set
+func regionParams(sep string) string {
+	values := make(url.Values)
+	if Country != "" {
+		values.Set("cc", Country)
+	}
+	if Language != "" {
+		values.Set("l", Language)
+	}
+	if len(values) < 1 {
+		return ""
+	}
+	return sep + values.Encode()
+}
+
 func tagReturnSuccess(root *html.Node, appid int) bool {
 	if root == nil {
 		return false
@@ -284,7 +485,7 @@ func tagReturnSuccess(root *html.Node, appid int) bool {
 	for e := root; e != nil; e = e.NextSibling {
 		if e.DataAtom == atom.Meta {
 			for _, a := range e.Attr {
-				if (a.Key == "content") && (strings.HasPrefix(a.Val, fmt.Sprintf("https://store.steampowered.com/app/%d", appid))) {
+				if (a.Key == "content") && isAppURL(a.Val, appid) {
 					return true
 				}
 			}
@@ -296,6 +497,17 @@ func tagReturnSuccess(root *html.Node, appid int) bool {
 	return false
 }
 
+// isAppURL determines whether the link points at the store page of the game,
+// regardless of the host so mirrors pass as well
+func isAppURL(s string, appid int) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	p := fmt.Sprintf("/%s/%d", endpoints.App, appid)
+	return (u.Path == p) || strings.HasPrefix(u.Path, p+"/")
+}
+
 func findTags(root *html.Node) []string {
 	ret := make([]string, 0, 8)
 	if root == nil {
@@ -318,6 +530,27 @@ func findTags(root *html.Node) []string {
 	return ret
 }
 
+func (m *gameMatcher) AtLeast(n int) []int {
+	log.WithFields(log.Fields{
+		"sections": len(*m),
+		"min":      n,
+	}).Debug("Computing games present in at least min sections")
+	counts := make(map[int]int)
+	for _, section := range *m {
+		for game := range *section {
+			counts[game]++
+		}
+	}
+
+	ret := make([]int, 0, 8)
+	for game, c := range counts {
+		if c >= n {
+			ret = append(ret, game)
+		}
+	}
+	return ret
+}
+
 func (m *gameMatcher) All() []int {
 	games := make(map[int]struct{})
 	for _, section := range *m {
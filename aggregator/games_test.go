@@ -0,0 +1,231 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gitlab.com/vultour/steamcli/steamerr"
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+// newGameServer returns a server with a profile owning a game available in the
+// store and one that isn't
+func newGameServer() *steamtest.Server {
+	s := steamtest.NewServer()
+	s.AddApp(&steamtest.App{AppID: 10, Name: "Counter-Strike", Tags: []string{"Action", "FPS"}})
+	s.AddApp(&steamtest.App{AppID: 20, Name: "Delisted", Unavailable: true})
+	s.AddProfile(&steamtest.Profile{
+		SteamID64: 76561197960287930,
+		Name:      "player",
+		Games: []steamtest.Game{
+			{AppID: 10, Name: "Counter-Strike", HoursTotal: 1},
+			{AppID: 20, Name: "Delisted", HoursTotal: 1},
+		},
+	})
+	return s
+}
+
+func TestUpdateGameCache(t *testing.T) {
+	s := newGameServer()
+	defer s.Close()
+	defer s.Use()()
+	a := newTestAggregator(t, s, "76561197960287930")
+	s.SetNullResponses(2) // Retried like rate limits
+
+	report, err := a.UpdateGameCache()
+	if err != nil {
+		t.Fatalf("UpdateGameCache() = %v", err)
+	}
+	if (report.Fetched != 2) || (report.Invalid != 1) || (report.Failed != 0) {
+		t.Errorf("UpdateGameCache() report = %s", report)
+	}
+
+	g, ok := a.Cache.Games.Get(10)
+	if !ok || g.Invalid || (g.Name != "Counter-Strike") {
+		t.Errorf("game 10 = %+v, %t", g, ok)
+	}
+	// success:false is backfilled from the profile
+	g, ok = a.Cache.Games.Get(20)
+	if !ok || !g.Invalid || (g.Name != "Delisted") {
+		t.Errorf("game 20 = %+v, %t", g, ok)
+	}
+	if pending := a.Cache.PendingGames(); len(pending) != 0 {
+		t.Errorf("games left pending: %v", pending)
+	}
+
+	// Everything is cached now
+	report, err = a.UpdateGameCache()
+	if (err != nil) || (report.Fetched != 0) || (report.Skipped != 2) {
+		t.Errorf("second UpdateGameCache() = %s, %v", report, err)
+	}
+}
+
+func TestUpdateGameCacheRegionUnavailable(t *testing.T) {
+	s := newGameServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddApp(&steamtest.App{
+		AppID:         30,
+		Name:          "Region Locked",
+		UnavailableIn: []string{"de"},
+		Details: map[string]interface{}{
+			"price_overview": map[string]interface{}{"currency": "USD", "final": 999},
+		},
+	})
+	a := newTestAggregator(t, s, "76561197960287930")
+	defer func(old string) { Country = old }(Country)
+
+	Country = "us"
+	if _, err := a.UpdateGames([]int{30}); err != nil {
+		t.Fatalf("UpdateGames() in us = %v", err)
+	}
+	Country = "de"
+	if _, err := a.UpdateGames([]int{30}); err != nil {
+		t.Fatalf("UpdateGames() in de = %v", err)
+	}
+
+	// The game cached from the other region is kept
+	g, ok := a.Cache.Games.Get(30)
+	if !ok || g.Invalid || (g.Name != "Region Locked") {
+		t.Fatalf("game 30 = %+v, %t", g, ok)
+	}
+	if p := g.PriceFor("us"); (p == nil) || (p.Final != 999) {
+		t.Errorf("game 30 us price = %+v", p)
+	}
+	if p, ok := g.Prices["de"]; !ok || (p != nil) {
+		t.Errorf("game 30 de price = %+v, %t, want no price", p, ok)
+	}
+
+	// The region isn't fetched again
+	report, err := a.UpdateGames([]int{30})
+	if (err != nil) || (report.Skipped != 1) {
+		t.Errorf("second UpdateGames() in de = %s, %v", report, err)
+	}
+
+	// Games that aren't cached yet are still stored as invalid
+	s.AddApp(&steamtest.App{AppID: 40, Name: "Never Sold", UnavailableIn: []string{"de"}})
+	if _, err := a.UpdateGames([]int{40}); err != nil {
+		t.Fatalf("UpdateGames() of a new game = %v", err)
+	}
+	if g, ok := a.Cache.Games.Get(40); !ok || !g.Invalid {
+		t.Errorf("game 40 = %+v, %t, want an invalid entry", g, ok)
+	}
+}
+
+func TestUpdateGameTags(t *testing.T) {
+	s := newGameServer()
+	defer s.Close()
+	defer s.Use()()
+	a := newTestAggregator(t, s, "76561197960287930")
+	if _, err := a.UpdateGameCache(); err != nil {
+		t.Fatalf("UpdateGameCache() = %v", err)
+	}
+
+	report, err := a.UpdateGameTags()
+	if err != nil {
+		t.Fatalf("UpdateGameTags() = %v", err)
+	}
+	if (report.Fetched != 2) || (report.Invalid != 1) || (report.Failed != 0) || (report.Err != nil) {
+		t.Errorf("UpdateGameTags() report = %s (%v)", report, report.Err)
+	}
+	g, _ := a.Cache.Games.Get(10)
+	if want := []string{"Action", "FPS"}; !reflect.DeepEqual(g.Tags, want) {
+		t.Errorf("game 10 tags = %v, want %v", g.Tags, want)
+	}
+	// The store redirects delisted games, they end up without tags
+	if g, _ := a.Cache.Games.Get(20); (g.Tags == nil) || (len(g.Tags) != 0) {
+		t.Errorf("game 20 tags = %#v, want none", g.Tags)
+	}
+
+	// Only games whose tags were never fetched are requested
+	before := countRequests(s, "/app/")
+	if _, err := a.UpdateGameTags(); err != nil {
+		t.Fatalf("second UpdateGameTags() = %v", err)
+	}
+	if n := countRequests(s, "/app/") - before; n != 0 {
+		t.Errorf("second UpdateGameTags() made %d requests", n)
+	}
+}
+
+func TestUpdateGameTagsFailed(t *testing.T) {
+	s := newGameServer()
+	defer s.Close()
+	defer s.Use()()
+	a := newTestAggregator(t, s, "76561197960287930")
+	if _, err := a.UpdateGames([]int{10}); err != nil {
+		t.Fatalf("UpdateGames() = %v", err)
+	}
+	s.Limiter.MaxRetries = 1
+	s.SetRateLimited(100)
+
+	report, err := a.UpdateGameTags()
+	if err != nil {
+		t.Fatalf("UpdateGameTags() = %v", err)
+	}
+	if (report.Failed != 1) || (report.Fetched != 0) || !errors.Is(report.Err, steamerr.ErrRateLimited) {
+		t.Errorf("UpdateGameTags() report = %s (%v), want a rate limited failure", report, report.Err)
+	}
+	// Failed games aren't mistaken for games without tags
+	if g, _ := a.Cache.Games.Get(10); g.Tags != nil {
+		t.Errorf("game 10 tags = %#v after a failure, want nil", g.Tags)
+	}
+
+	s.SetRateLimited(0)
+	report, err = a.UpdateGameTags()
+	if (err != nil) || (report.Fetched != 1) || (report.Failed != 0) {
+		t.Errorf("second UpdateGameTags() = %s, %v", report, err)
+	}
+	if g, _ := a.Cache.Games.Get(10); len(g.Tags) != 2 {
+		t.Errorf("game 10 tags = %v after retrying", g.Tags)
+	}
+}
+
+func TestUpdateGamesRateLimited(t *testing.T) {
+	s := newGameServer()
+	defer s.Close()
+	defer s.Use()()
+	a := newTestAggregator(t, s, "76561197960287930")
+	s.Limiter.MaxRetries = 1
+	s.SetRateLimited(100)
+
+	report, err := a.UpdateGames([]int{10})
+	if err != nil {
+		t.Fatalf("UpdateGames() = %v", err)
+	}
+	if (report.Failed != 1) || !errors.Is(report.Err, steamerr.ErrRateLimited) {
+		t.Errorf("UpdateGames() report = %s (%v), want a rate limited failure", report, report.Err)
+	}
+	if pending := a.Cache.PendingGames(); !reflect.DeepEqual(pending, []int{10}) {
+		t.Errorf("pending games = %v, want the failed game", pending)
+	}
+
+	s.SetRateLimited(0)
+	report, err = a.ResumeGames()
+	if (err != nil) || (report.Fetched != 1) {
+		t.Errorf("ResumeGames() = %s, %v", report, err)
+	}
+}
+
+func TestUpdateGamesSlowResponse(t *testing.T) {
+	s := newGameServer()
+	defer s.Close()
+	defer s.Use()()
+	a := newTestAggregator(t, s, "76561197960287930")
+	s.SetDelay(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report, err := a.UpdateGamesContext(ctx, []int{10, 20})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("UpdateGamesContext() error = %v, want the deadline", err)
+	}
+	if report.Failed != 0 {
+		t.Errorf("cancelled games were counted as failed: %s", report)
+	}
+	if pending := a.Cache.PendingGames(); !reflect.DeepEqual(pending, []int{10, 20}) {
+		t.Errorf("pending games = %v, want both", pending)
+	}
+}
@@ -0,0 +1,85 @@
+package aggregator
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"gitlab.com/vultour/steamcli/api/group"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The following variables are the defaults used for adding group members
+var (
+	MaxMembers        = 100
+	MemberConcurrency = 4
+)
+
+// AddGroup adds up to max members of the specified group as clients to the
+// Aggregator, fetching concurrency profiles at the same time
+// Members with private profiles or game lists are skipped. Returns the number
+// of added members.
+func (a *Aggregator) AddGroup(id string, max, concurrency int) (int, error) {
+	return a.AddGroupContext(context.Background(), id, max, concurrency)
+}
+
+// AddGroupContext is like AddGroup, the requests are cancelled once ctx is
+// done
+func (a *Aggregator) AddGroupContext(ctx context.Context, id string, max, concurrency int) (int, error) {
+	var c *group.Client
+	a.Cache.Lock()
+	g, found := a.Cache.Groups.Find(group.NormalizeID(id), max)
+	a.Cache.Unlock()
+	if found {
+		log.WithField("name", g.Name).Debug("Reusing cached group")
+		c = group.NewClientPre(g)
+	} else {
+		var err error
+		c, err = group.NewClientContext(ctx, id, max)
+		if err != nil {
+			return 0, err
+		}
+		a.Cache.Lock()
+		a.Cache.Groups.Add(&c.Group)
+		a.Cache.Unlock()
+	}
+
+	members := c.Group.Members
+	if (max > 0) && (len(members) > max) {
+		members = members[:max]
+	}
+	log.WithFields(log.Fields{
+		"group":       c.Group.Name,
+		"members":     len(members),
+		"concurrency": concurrency,
+	}).Debug("Adding group members")
+
+	var mu sync.Mutex
+	n := 0
+	err := runWorkers(ctx, concurrency, len(members), func(i int) error {
+		mid := strconv.FormatInt(members[i], 10)
+		added, err := a.addPublicClient(ctx, mid)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id":  mid,
+				"err": err,
+			}).Info("Skipping group member")
+			return nil
+		}
+		if added {
+			mu.Lock()
+			n++
+			mu.Unlock()
+		}
+		return nil
+	})
+
+	if serr := a.Cache.Save(); serr != nil {
+		return n, serr
+	}
+	return n, err
+}
@@ -0,0 +1,117 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+// newGroupServer returns a server with a group whose members are public,
+// private, hide their games, or don't exist
+func newGroupServer() *steamtest.Server {
+	s := steamtest.NewServer()
+	games := []steamtest.Game{{AppID: 10, Name: "Counter-Strike", HoursTotal: 1}}
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287930, Name: "public", Games: games})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287931, Name: "private", Private: true})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287932, Name: "hidden", GamesPrivate: true})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287933, Name: "another", Games: games})
+	s.AddGroup(&steamtest.Group{
+		GroupID64: 103582791429521412,
+		Name:      "Valve",
+		URL:       "valve",
+		Members: []int64{
+			76561197960287930,
+			76561197960287931,
+			76561197960287932,
+			76561197960287934, // Doesn't exist
+			76561197960287933,
+		},
+		PageSize: 2,
+	})
+	return s
+}
+
+func TestAddGroup(t *testing.T) {
+	tests := []struct {
+		id          string
+		max         int
+		concurrency int
+		added       []string
+	}{
+		{"valve", 0, 2, []string{"76561197960287930", "76561197960287933"}},
+		{"103582791429521412", 0, 1, []string{"76561197960287930", "76561197960287933"}},
+		{"[g:1:4]", 0, 0, []string{"76561197960287930", "76561197960287933"}},
+		{"valve", 2, 4, []string{"76561197960287930"}},
+	}
+	for _, tt := range tests {
+		s := newGroupServer()
+		restore := s.Use()
+		a := newTestAggregator(t, s)
+
+		n, err := a.AddGroup(tt.id, tt.max, tt.concurrency)
+		if err != nil {
+			t.Errorf("AddGroup(%q, %d) = %v", tt.id, tt.max, err)
+		}
+		got := make([]string, 0, len(a.Clients))
+		for id := range a.Clients {
+			got = append(got, id)
+		}
+		sort.Strings(got)
+		if (n != len(tt.added)) || (len(got) != len(tt.added)) {
+			t.Errorf("AddGroup(%q, %d) added %d: %v, want %v", tt.id, tt.max, n, got, tt.added)
+		} else {
+			for i := range got {
+				if got[i] != tt.added[i] {
+					t.Errorf("AddGroup(%q, %d) added %v, want %v", tt.id, tt.max, got, tt.added)
+					break
+				}
+			}
+		}
+
+		// The member list is cached, the clients are already there
+		before := countRequests(s, "/memberslistxml/")
+		if n, err := a.AddGroup(tt.id, tt.max, tt.concurrency); (n != 0) || (err != nil) {
+			t.Errorf("second AddGroup(%q, %d) = %d, %v", tt.id, tt.max, n, err)
+		}
+		if n := countRequests(s, "/memberslistxml/") - before; n != 0 {
+			t.Errorf("second AddGroup(%q, %d) requested %d member pages", tt.id, tt.max, n)
+		}
+		restore()
+		s.Close()
+	}
+}
+
+func TestAddGroupCancelled(t *testing.T) {
+	s := newGroupServer()
+	defer s.Close()
+	defer s.Use()()
+	a := newTestAggregator(t, s)
+	a.Cache.Groups.Add(&objects.XMLGroup{
+		GroupID64:   103582791429521412,
+		Name:        "Valve",
+		URL:         "valve",
+		MemberCount: 2,
+		Members:     []int64{76561197960287930, 76561197960287933},
+		Updated:     time.Now(),
+	})
+	s.SetDelay(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	n, err := a.AddGroupContext(ctx, "valve", 0, 2)
+	if (n != 0) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AddGroupContext() = %d, %v, want the deadline", n, err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("AddGroupContext() returned %s after the deadline", d)
+	}
+	if len(a.Clients) != 0 {
+		t.Errorf("clients were added after the deadline: %v", a.Clients)
+	}
+}
@@ -0,0 +1,78 @@
+package aggregator
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress describes how far a running update got
+type Progress struct {
+	Task    string // What is being updated, "games", "tags", or "reviews"
+	Done    int    // Games processed so far, including failed ones
+	Total   int
+	Failed  int
+	Current int    // App ID of the game processed last
+	Name    string // Name of the game processed last, if known
+	Elapsed time.Duration
+}
+
+// Rate returns how many games are processed per second
+func (p Progress) Rate() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Done) / p.Elapsed.Seconds()
+}
+
+// ETA returns the estimated time until the update finishes, 0 if unknown
+func (p Progress) ETA() time.Duration {
+	if p.Done < 1 {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) / float64(p.Done) * float64(p.Total-p.Done))
+}
+
+// ProgressFunc receives progress updates, see Aggregator.OnProgress
+type ProgressFunc func(Progress)
+
+// progressTracker counts processed games and reports them to a ProgressFunc
+type progressTracker struct {
+	fn    ProgressFunc
+	mu    sync.Mutex
+	p     Progress
+	start time.Time
+}
+
+// newProgress starts tracking the task and reports that nothing was done yet
+// fn may be nil, in which case nothing is reported.
+func newProgress(fn ProgressFunc, task string, total int) *progressTracker {
+	t := &progressTracker{
+		fn:    fn,
+		p:     Progress{Task: task, Total: total},
+		start: time.Now(),
+	}
+	t.report()
+	return t
+}
+
+// step records n processed games, the last of which was appid
+func (t *progressTracker) step(n, appid int, name string, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p.Done += n
+	if failed {
+		t.p.Failed += n
+	}
+	t.p.Current = appid
+	t.p.Name = name
+	t.report()
+}
+
+// report calls fn, the caller holds mu unless no other goroutine uses t yet
+func (t *progressTracker) report() {
+	if t.fn == nil {
+		return
+	}
+	t.p.Elapsed = time.Since(t.start)
+	t.fn(t.p)
+}
@@ -0,0 +1,50 @@
+package aggregator
+
+import (
+	"sort"
+)
+
+// RecentGame is a game played in the last two weeks by at least one client
+type RecentGame struct {
+	AppID int
+	Name  string
+	Hours float64            // Combined across all clients
+	Split map[string]float64 // Hours keyed by client ID
+}
+
+// Recent returns games played in the last two weeks across all clients,
+// sorted by the combined hours played
+func (a *Aggregator) Recent() []*RecentGame {
+	games := make(map[int]*RecentGame)
+	for id, c := range a.Clients {
+		for appid, g := range c.Profile.Games {
+			h := g.HoursTwoWeeks()
+			if h <= 0 {
+				continue
+			}
+			rg, ok := games[appid]
+			if !ok {
+				rg = &RecentGame{
+					AppID: appid,
+					Name:  g.Name,
+					Split: make(map[string]float64),
+				}
+				games[appid] = rg
+			}
+			rg.Hours += h
+			rg.Split[id] = h
+		}
+	}
+
+	ret := make([]*RecentGame, 0, len(games))
+	for _, rg := range games {
+		ret = append(ret, rg)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Hours == ret[j].Hours {
+			return ret[i].AppID < ret[j].AppID
+		}
+		return ret[i].Hours > ret[j].Hours
+	})
+	return ret
+}
@@ -0,0 +1,46 @@
+package aggregator
+
+import (
+	"testing"
+
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+func TestRecentFromCachedProfile(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddProfile(&steamtest.Profile{
+		SteamID64: 76561197960287930,
+		Name:      "player",
+		Games: []steamtest.Game{
+			{AppID: 10, Name: "Counter-Strike", HoursTotal: 100, HoursTwoWeeks: 2.5},
+			{AppID: 20, Name: "Team Fortress Classic", HoursTotal: 3},
+		},
+	})
+	a := newTestAggregator(t, s, "76561197960287930")
+	if err := a.Cache.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	cached, err := New()
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	if err := cached.AddClient("76561197960287930"); err != nil {
+		t.Fatalf("AddClient() = %v", err)
+	}
+	if n := countRequests(s, "/profiles/76561197960287930?"); n != 1 {
+		t.Fatalf("profile was requested %d times, want it cached after the first", n)
+	}
+
+	for name, agg := range map[string]*Aggregator{"fresh": a, "cached": cached} {
+		recent := agg.Recent()
+		if len(recent) != 1 {
+			t.Fatalf("%s: got %d recent games, want 1", name, len(recent))
+		}
+		if (recent[0].AppID != 10) || (recent[0].Hours != 2.5) {
+			t.Errorf("%s: unexpected recent game %+v", name, recent[0])
+		}
+	}
+}
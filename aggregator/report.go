@@ -0,0 +1,38 @@
+package aggregator
+
+import (
+	"fmt"
+	"sync"
+)
+
+// UpdateReport summarises an update of the game cache or the game tags
+type UpdateReport struct {
+	Fetched int // Stored in the cache, including invalid games
+	Invalid int // Not available in the store, backfilled from profiles or left without tags
+	Failed  int // Could not be fetched, left in the pending queue or without tags until the next update
+	Skipped int // Already cached and up to date
+
+	Err error // Why the first failed game couldn't be fetched, nil if none did
+
+	mu sync.Mutex
+}
+
+// String returns a one line summary of the report
+func (r *UpdateReport) String() string {
+	return fmt.Sprintf(
+		"%d fetched (%d invalid), %d failed, %d up to date",
+		r.Fetched, r.Invalid, r.Failed, r.Skipped,
+	)
+}
+
+// add records the outcome of one request, err is why it failed if it did
+func (r *UpdateReport) add(fetched, invalid, failed int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Fetched += fetched
+	r.Invalid += invalid
+	r.Failed += failed
+	if r.Err == nil {
+		r.Err = err
+	}
+}
@@ -0,0 +1,114 @@
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/cache"
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UpdateGameReviews fetches the user review summary for all valid cached games
+// that don't have one or where it is older than cache.MaxReviewAge
+// Up to Workers requests are made at the same time.
+func (a *Aggregator) UpdateGameReviews() error {
+	return a.UpdateGameReviewsContext(context.Background())
+}
+
+// UpdateGameReviewsContext is like UpdateGameReviews, the requests are
+// cancelled once ctx is done
+func (a *Aggregator) UpdateGameReviewsContext(ctx context.Context) error {
+	log.Debug("Updating reviews")
+	c, err := newStoreClient()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int, 0, 16)
+	a.Cache.RLock()
+	for i, g := range a.Cache.Games {
+		if !g.Invalid && cache.ReviewsExpired(g) {
+			ids = append(ids, i)
+		}
+	}
+	a.Cache.RUnlock()
+
+	progress := newProgress(a.OnProgress, "reviews", len(ids))
+	err = runWorkers(ctx, Workers, len(ids), func(n int) error {
+		i := ids[n]
+		r, err := a.flights.Do("reviews:"+strconv.Itoa(i), func() (interface{}, error) {
+			return fetchReviews(ctx, c, i)
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		progress.step(1, i, a.gameName(i), err != nil)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"err": err,
+				"id":  i,
+			}).Error("Failed fetching reviews")
+			return nil
+		}
+		reviews := r.(*objects.JSONGameReviews)
+		log.WithFields(log.Fields{
+			"id":      i,
+			"reviews": reviews.ScoreDescription,
+		}).Debug("Retrieved reviews")
+
+		a.Cache.Lock()
+		if g, cached := a.Cache.Games.Get(i); cached {
+			g.Reviews = reviews
+			a.Cache.Changed()
+		}
+		a.Cache.Unlock()
+		return a.Cache.Checkpoint()
+	})
+	if serr := a.Cache.Save(); serr != nil {
+		return serr
+	}
+	return err
+}
+
+func fetchReviews(ctx context.Context, httpClient *http.Client, appid int) (*objects.JSONGameReviews, error) {
+	log.WithField("appid", appid).Debug("Retrieving reviews")
+	u := fmt.Sprintf(
+		"%s/%d?json=1&language=all&purchase_type=all&num_per_page=0&filter=summary",
+		storeURL(endpoints.AppReviews), appid,
+	)
+	r, err := storeGet(ctx, httpClient, u)
+	if err != nil {
+		return nil, steamerr.Wrap(steamerr.ErrNetwork, "could not retrieve data from the store", err)
+	}
+	defer r.Body.Close()
+
+	log.WithField("status", r.StatusCode).Debug("Got response")
+
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, steamerr.Wrap(steamerr.ErrNetwork, "couldn't read response body", err)
+	}
+
+	obj := struct {
+		Success int                     `json:"success"`
+		Summary objects.JSONGameReviews `json:"query_summary"`
+	}{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, steamerr.Wrap(steamerr.ErrDecode, "couldn't decode json", err)
+	}
+	if obj.Success != 1 {
+		return nil, steamerr.New(steamerr.ErrNotFound, "store returned unsuccessful response")
+	}
+
+	obj.Summary.Updated = time.Now()
+	return &obj.Summary, nil
+}
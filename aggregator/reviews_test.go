@@ -0,0 +1,40 @@
+package aggregator
+
+import (
+	"testing"
+
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+func TestUpdateGameReviews(t *testing.T) {
+	s := newGameServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddApp(&steamtest.App{AppID: 10, Name: "Counter-Strike", Positive: 90, Negative: 10})
+	a := newTestAggregator(t, s, "76561197960287930")
+	if _, err := a.UpdateGameCache(); err != nil {
+		t.Fatalf("UpdateGameCache() = %v", err)
+	}
+
+	if err := a.UpdateGameReviews(); err != nil {
+		t.Fatalf("UpdateGameReviews() = %v", err)
+	}
+	g, _ := a.Cache.Games.Get(10)
+	if (g.Reviews == nil) || (g.Reviews.TotalReviews != 100) || (g.ReviewPercent() != 90) ||
+		(g.Reviews.ScoreDescription != "Very Positive") || g.Reviews.Updated.IsZero() {
+		t.Errorf("game 10 reviews = %+v", g.Reviews)
+	}
+	// Invalid games aren't requested
+	if g, _ := a.Cache.Games.Get(20); (g.Reviews != nil) || (countRequests(s, "/appreviews/20") != 0) {
+		t.Errorf("game 20 reviews = %+v", g.Reviews)
+	}
+
+	// Fresh reviews aren't requested again
+	before := countRequests(s, "/appreviews/")
+	if err := a.UpdateGameReviews(); err != nil {
+		t.Fatalf("second UpdateGameReviews() = %v", err)
+	}
+	if n := countRequests(s, "/appreviews/") - before; n != 0 {
+		t.Errorf("second UpdateGameReviews() made %d requests", n)
+	}
+}
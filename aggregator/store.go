@@ -0,0 +1,70 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"time"
+
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/steamerr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// storeTimeout is the timeout limit for one store request
+const storeTimeout = time.Second * 10
+
+// newStoreClient returns an HTTP client with cookies passing the age check
+func newStoreClient() (*http.Client, error) {
+	c := endpoints.NewHTTPClient(storeTimeout)
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cookiejar: %w", err)
+	}
+
+	c.Jar = jar
+	u, err := url.Parse(endpoints.Config.Store)
+	if err != nil {
+		return nil, steamerr.Wrap(steamerr.ErrInvalid, "Could not parse store URL", err)
+	}
+	// Host-only cookies, so they're sent to mirrors as well
+	c.Jar.SetCookies(
+		u,
+		[]*http.Cookie{
+			&http.Cookie{
+				Name:    "birthtime",
+				Expires: time.Now().Add(time.Hour * 12),
+				Path:    "/",
+				Value:   "156729601",
+			},
+			&http.Cookie{
+				Name:    "lastagecheckage",
+				Expires: time.Now().Add(time.Hour * 12),
+				Path:    "/",
+				Value:   "1-0-1987",
+			},
+			&http.Cookie{
+				Name:    "wants_mature_content",
+				Expires: time.Now().Add(time.Hour * 12),
+				Path:    "/",
+				Value:   "1",
+			},
+		},
+	)
+	return c, nil
+}
+
+// storeURL returns the URL of the path under the configured store root
+func storeURL(path string) string {
+	return fmt.Sprintf("%s/%s", endpoints.Config.Store, path)
+}
+
+// storeGet performs a GET request against the store, requests are spaced out
+// and retried by the client's transport (see endpoints.Config.Limiter)
+func storeGet(ctx context.Context, c *http.Client, u string) (*http.Response, error) {
+	log.Debugf("Built URL: %s", u)
+	return endpoints.Get(ctx, c, u)
+}
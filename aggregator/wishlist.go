@@ -0,0 +1,90 @@
+package aggregator
+
+import (
+	"context"
+	"sort"
+
+	"gitlab.com/vultour/steamcli/api/profile"
+	"gitlab.com/vultour/steamcli/objects"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// UpdateWishlists fetches the wishlists of all clients that don't have one
+// cached yet
+func (a *Aggregator) UpdateWishlists() error {
+	return a.UpdateWishlistsContext(context.Background())
+}
+
+// UpdateWishlistsContext is like UpdateWishlists, the requests are cancelled
+// once ctx is done
+func (a *Aggregator) UpdateWishlistsContext(ctx context.Context) error {
+	for id, c := range a.Clients {
+		if c.Profile.Wishlist != nil {
+			log.WithField("id", id).Debug("Reusing cached wishlist")
+			continue
+		}
+		if err := c.GetWishlistContext(ctx); err != nil {
+			return err
+		}
+		a.cacheProfile(&c.Profile)
+	}
+	return a.Cache.Save()
+}
+
+// Wishlist returns the wishlist of the specified profile
+// The profile is not added to the Aggregator as a client.
+func (a *Aggregator) Wishlist(id string) (objects.JSONWishlist, error) {
+	return a.WishlistContext(context.Background(), id)
+}
+
+// WishlistContext is like Wishlist, the requests are cancelled once ctx is
+// done
+func (a *Aggregator) WishlistContext(ctx context.Context, id string) (objects.JSONWishlist, error) {
+	c, ok := a.Clients[id]
+	if !ok {
+		var err error
+		c, err = a.client(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.Profile.Wishlist == nil {
+		if err := c.GetWishlistContext(ctx); err != nil {
+			return nil, err
+		}
+		a.cacheProfile(&c.Profile)
+		if err := a.Cache.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return c.Profile.Wishlist, nil
+}
+
+// SelectWishlist returns games wishlisted across all profiles matching the
+// specified criteria
+func (a *Aggregator) SelectWishlist(tags []string, common, and, invalid bool) objects.JSONGameList {
+	return a.selectFrom(
+		func(c *profile.Client) []int {
+			ret := make([]int, 0, len(c.Profile.Wishlist))
+			for id := range c.Profile.Wishlist {
+				ret = append(ret, id)
+			}
+			return ret
+		},
+		tags, common, 0, and, invalid,
+	)
+}
+
+// WishedBy returns the names of all clients that have the game wishlisted
+func (a *Aggregator) WishedBy(appid int) []string {
+	ret := make([]string, 0, 2)
+	for _, c := range a.Clients {
+		if _, ok := c.Profile.Wishlist[appid]; ok {
+			ret = append(ret, c.Profile.SteamID)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,101 @@
+package aggregator
+
+import (
+	"context"
+	"sync"
+)
+
+// Workers is how many store requests are made at the same time
+// All workers share the rate limiter of the HTTP clients, more workers only
+// help while requests are waiting on the network.
+var Workers = 4
+
+// runWorkers calls fn for every job index using up to n goroutines
+// No new jobs are started after one fails or ctx is done, the first error is
+// returned.
+func runWorkers(ctx context.Context, n, jobs int, fn func(i int) error) error {
+	if n < 1 {
+		n = 1
+	}
+	if n > jobs {
+		n = jobs
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	failed := make(chan struct{})
+
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for i := 0; i < jobs; i++ {
+		select {
+		case next <- i:
+		case <-failed:
+			break dispatch
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(next)
+	wg.Wait()
+	if firstErr == nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// flightGroup deduplicates concurrent calls doing the same work, e.g.
+// fetching the same game
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flight
+}
+
+// flight is a call in progress or just finished
+type flight struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// Do calls fn unless a call with the same key is already in progress, in which
+// case it waits for that call and returns its result instead
+func (g *flightGroup) Do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flight)
+	}
+	if f, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		f.wg.Wait()
+		return f.val, f.err
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	g.calls[key] = f
+	g.mu.Unlock()
+
+	f.val, f.err = fn()
+	f.wg.Done()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	return f.val, f.err
+}
@@ -1,10 +1,137 @@
 package endpoints
 
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gitlab.com/vultour/steamcli/ratelimit"
+)
+
+// Following constants are the default roots of the Steam services
+const (
+	DefaultCommunity = "https://steamcommunity.com"
+	DefaultStore     = "https://store.steampowered.com"
+	DefaultWebAPI    = "https://api.steampowered.com"
+)
+
+// Following constants name the environment variables overriding the roots of
+// the Steam services
+const (
+	EnvCommunity = "STEAMCLI_COMMUNITY_URL"
+	EnvStore     = "STEAMCLI_STORE_URL"
+	EnvWebAPI    = "STEAMCLI_WEBAPI_URL"
+)
+
 // Following constants describe the steamcommunity endpoints
 const (
-	Base  = "https://steamcommunity.com"
 	Alias = "id"
 	ID    = "profiles"
 
-	Games = "games"
+	Games   = "games"
+	Friends = "friends"
+	Stats   = "stats"
+
+	Group       = "groups"
+	GroupID     = "gid"
+	GroupMember = "memberslistxml"
+)
+
+// Following constants describe the store endpoints
+const (
+	AppDetails = "api/appdetails"
+	App        = "app"
+	AppReviews = "appreviews"
 )
+
+// Following constants describe the Steam Web API methods
+const (
+	ResolveVanityURL   = "ISteamUser/ResolveVanityURL/v1"
+	GetPlayerSummaries = "ISteamUser/GetPlayerSummaries/v2"
+	GetOwnedGames      = "IPlayerService/GetOwnedGames/v1"
+	GetWishlist        = "IWishlistService/GetWishlist/v1" // Doesn't need a key
+)
+
+// Settings describes where requests are sent and how
+// Every root can be pointed at a compatible server, e.g. a mirror or a local
+// stand-in for testing.
+type Settings struct {
+	Community string // Root of the Steam Community
+	Store     string // Root of the Steam Store
+	WebAPI    string // Root of the official Steam Web API
+
+	// Client is used for all requests if set, otherwise a new client with
+	// Transport is created for each API client
+	Client    *http.Client
+	Transport http.RoundTripper // nil for http.DefaultTransport
+
+	// Limiter spaces out and retries all requests, nil disables both
+	Limiter *ratelimit.Limiter
+}
+
+// Config is used by all API clients, change it before creating any
+var Config = Default()
+
+// Default returns the settings pointing at the Steam services
+func Default() Settings {
+	return Settings{
+		Community: DefaultCommunity,
+		Store:     DefaultStore,
+		WebAPI:    DefaultWebAPI,
+		Limiter:   ratelimit.Default,
+	}
+}
+
+// FromEnv replaces the roots for which the environment variable is set
+func (s *Settings) FromEnv() {
+	s.Set(os.Getenv(EnvCommunity), os.Getenv(EnvStore), os.Getenv(EnvWebAPI))
+}
+
+// Set replaces the roots that aren't empty, trailing slashes are removed
+func (s *Settings) Set(community, store, webAPI string) {
+	if community != "" {
+		s.Community = strings.TrimRight(community, "/")
+	}
+	if store != "" {
+		s.Store = strings.TrimRight(store, "/")
+	}
+	if webAPI != "" {
+		s.WebAPI = strings.TrimRight(webAPI, "/")
+	}
+}
+
+// NewHTTPClient returns a client used for requests made with the current
+// Config, the timeout only applies if Config.Client isn't set
+// A copy of the configured client is returned so callers can set its cookie
+// jar. With a Limiter the timeout applies to every attempt separately as
+// retries can take much longer.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	c := http.Client{
+		Timeout:   timeout,
+		Transport: Config.Transport,
+	}
+	if Config.Client != nil {
+		c = *Config.Client
+	}
+	if Config.Limiter != nil {
+		c.Transport = &ratelimit.Transport{
+			Base:    c.Transport,
+			Limiter: Config.Limiter,
+			Timeout: c.Timeout,
+		}
+		c.Timeout = 0
+	}
+	return &c
+}
+
+// Get performs a GET request using the client, the request is cancelled once
+// ctx is done
+func Get(ctx context.Context, c *http.Client, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
@@ -0,0 +1,77 @@
+package endpoints
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSettingsSet(t *testing.T) {
+	s := Default()
+	s.Set("http://127.0.0.1:8080/", "", "http://api.example.com//")
+	if s.Community != "http://127.0.0.1:8080" {
+		t.Errorf("Community = %s", s.Community)
+	}
+	if s.Store != DefaultStore {
+		t.Errorf("Store = %s, want the default", s.Store)
+	}
+	if s.WebAPI != "http://api.example.com" {
+		t.Errorf("WebAPI = %s", s.WebAPI)
+	}
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	t.Setenv(EnvCommunity, "")
+	t.Setenv(EnvStore, "http://store.example.com/")
+	t.Setenv(EnvWebAPI, "")
+	s := Default()
+	s.FromEnv()
+	if (s.Community != DefaultCommunity) || (s.Store != "http://store.example.com") ||
+		(s.WebAPI != DefaultWebAPI) {
+		t.Errorf("FromEnv() = %+v", s)
+	}
+}
+
+// recordingTransport counts the requests passing through it
+type recordingTransport struct {
+	requests int
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestNewHTTPClientUsesConfig(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	old := Config
+	defer func() { Config = old }()
+
+	rt := &recordingTransport{}
+	for name, s := range map[string]Settings{
+		"transport": {Transport: rt},
+		"client":    {Client: &http.Client{Transport: rt}},
+	} {
+		Config = s
+		before := rt.requests
+		resp, err := Get(context.Background(), NewHTTPClient(time.Second), srv.URL)
+		if err != nil {
+			t.Fatalf("%s: Get() = %v", name, err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != "ok" {
+			t.Errorf("%s: got body %q", name, b)
+		}
+		if rt.requests != before+1 {
+			t.Errorf("%s: the request didn't go through the configured transport", name)
+		}
+	}
+}
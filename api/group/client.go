@@ -0,0 +1,140 @@
+// Package group implements requests against Steam community groups
+package group
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/api/profile"
+	steamid "gitlab.com/vultour/steamcli/id"
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Client is a struct used for interacting with a group
+type Client struct {
+	HTTPClient *http.Client
+	baseURL    string
+	Group      objects.XMLGroup
+}
+
+// NewClient returns a new Client for the group and retrieves up to max of its
+// members (0 for all of them)
+// The id is either the group's ID in any form understood by the id package
+// (e.g. 64bit or [g:1:Z]) or the group's custom URL name.
+func NewClient(id string, max int) (*Client, error) {
+	return NewClientContext(context.Background(), id, max)
+}
+
+// NewClientContext is like NewClient, the requests are cancelled once ctx is
+// done
+func NewClientContext(ctx context.Context, id string, max int) (*Client, error) {
+	client := &Client{
+		HTTPClient: endpoints.NewHTTPClient(profile.RequestTimeout),
+	}
+
+	id = NormalizeID(id)
+	if _, err := strconv.ParseUint(id, 10, 64); err == nil {
+		log.WithField("id", id).Debug("Attempting to use ID as 64bit group ID")
+		client.baseURL = fmt.Sprintf("%s/%s/%s", endpoints.Config.Community, endpoints.GroupID, id)
+	} else {
+		log.WithField("id", id).Debug("Attempting to use ID as group name")
+		client.baseURL = fmt.Sprintf("%s/%s/%s", endpoints.Config.Community, endpoints.Group, id)
+	}
+
+	if err := client.GetMembersContext(ctx, max); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NormalizeID returns the 64bit ID of the group if id is a group ID in any form
+// understood by the id package, otherwise the custom URL name in id
+func NormalizeID(id string) string {
+	if x, err := steamid.New(id); (err == nil) && (x.Full.Type == steamid.AccountClan) {
+		return strconv.FormatUint(x.Full.Get(), 10)
+	}
+	return id
+}
+
+// NewClientPre creates a client using an existing group
+// This does not perform a web request unless manually triggered.
+func NewClientPre(g *objects.XMLGroup) *Client {
+	return &Client{
+		HTTPClient: endpoints.NewHTTPClient(profile.RequestTimeout),
+		baseURL: fmt.Sprintf(
+			"%s/%s/%d",
+			endpoints.Config.Community, endpoints.GroupID, g.GroupID64,
+		),
+		Group: *g,
+	}
+}
+
+// GetMembers retrieves up to max of the group's members (0 for all of them)
+// The member list is paginated, one request is made per page.
+func (c *Client) GetMembers(max int) error {
+	return c.GetMembersContext(context.Background(), max)
+}
+
+// GetMembersContext is like GetMembers, the requests are cancelled once ctx is
+// done
+func (c *Client) GetMembersContext(ctx context.Context, max int) error {
+	members := make([]int64, 0, 64)
+	var group objects.XMLGroup
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/%s/?xml=1&p=%d", c.baseURL, endpoints.GroupMember, page)
+		log.WithField("url", u).Debug("Built URL")
+		response, err := endpoints.Get(ctx, c.HTTPClient, u)
+		if err != nil {
+			return &profile.RequestError{
+				Detail:     "Could not perform request",
+				Underlying: err,
+				Class:      steamerr.ErrNetwork,
+			}
+		}
+
+		group = objects.XMLGroup{}
+		err = xml.NewDecoder(response.Body).Decode(&group)
+		response.Body.Close()
+		if err != nil {
+			return &profile.RequestError{
+				Detail:     "Could not decode group member list",
+				Underlying: err,
+				Class:      steamerr.ErrDecode,
+			}
+		}
+		if group.GroupID64 == 0 {
+			return &profile.RequestError{Detail: "Could not decode group", Class: steamerr.ErrDecode}
+		}
+		log.WithFields(log.Fields{
+			"page":    group.CurrentPage,
+			"pages":   group.TotalPages,
+			"members": len(group.Members),
+		}).Debug("Retrieved group members")
+
+		members = append(members, group.Members...)
+		if (max > 0) && (len(members) >= max) {
+			members = members[:max]
+			break
+		}
+		if (len(group.Members) < 1) || (group.CurrentPage >= group.TotalPages) {
+			break
+		}
+	}
+
+	group.Members = members
+	group.Updated = time.Now()
+	c.Group = group
+	c.baseURL = fmt.Sprintf(
+		"%s/%s/%d",
+		endpoints.Config.Community, endpoints.GroupID, group.GroupID64,
+	)
+	return nil
+}
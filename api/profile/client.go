@@ -1,16 +1,21 @@
 package profile
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/id"
 	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -19,18 +24,41 @@ import (
 type Client struct {
 	HTTPClient *http.Client
 	baseURL    string
+	webAPI     bool // Use the Web API instead of community XML
 	Profile    objects.XMLProfile
 }
 
 // RequestError is returned when a request fails
 type RequestError struct {
 	Detail     string
-	Underlying error
+	Underlying error // May be nil
+	Class      error // One of the steamerr classes, nil if unknown
+}
+
+// The following errors describe why a profile cannot be used
+// They're wrapped in a ProfileError, test for them or their steamerr class
+// using errors.Is.
+var (
+	ErrProfileNotFound = steamerr.New(steamerr.ErrNotFound, "profile not found")
+	ErrProfilePrivate  = steamerr.New(steamerr.ErrPrivate, "profile is private")
+	ErrGamesPrivate    = steamerr.New(steamerr.ErrPrivate, "game details are private")
+	ErrFriendsPrivate  = steamerr.New(steamerr.ErrPrivate, "friends list is private")
+	ErrStatsNotFound   = steamerr.New(steamerr.ErrNotFound, "game stats not available")
+)
+
+// ProfileError is returned when a profile doesn't exist or isn't public
+type ProfileError struct {
+	ID  string
+	Err error // One of the ErrProfile*/ErrGames*/ErrFriends*/ErrStats* values
 }
 
 var (
 	// RequestTimeout is the timeout limit for one request
 	RequestTimeout = 15 * time.Second
+
+	// APIKey is the Steam Web API key, if set the Web API is used instead of
+	// the community XML endpoints
+	APIKey = ""
 )
 
 func buildURL(URL string, params map[string]string) string {
@@ -46,75 +74,50 @@ func buildURL(URL string, params map[string]string) string {
 
 // NewClient returns a new steamcli Client used for API requests
 func NewClient(id string) (*Client, error) {
+	return NewClientContext(context.Background(), id)
+}
+
+// NewClientContext is like NewClient, the requests are cancelled once ctx is
+// done
+func NewClientContext(ctx context.Context, id string) (*Client, error) {
 	client := &Client{
-		HTTPClient: &http.Client{
-			Timeout: RequestTimeout,
-		},
-		baseURL: "",
-	}
-
-	if _, err := strconv.ParseUint(id, 10, 64); err == nil {
-		log.WithField("id", id).Debug("Attempting to use ID as 64bit")
-		resp, err := http.Get(
-			buildURL(
-				fmt.Sprintf("%s/%s/%s", endpoints.Base, endpoints.ID, id),
-				nil,
-			),
-		)
-		log.Debugf("Retrieving %s", resp.Request.URL)
-		if err != nil {
-			return nil, &RequestError{
-				Detail:     "Could not perform request",
-				Underlying: err,
-			}
-		}
+		HTTPClient: endpoints.NewHTTPClient(RequestTimeout),
+		baseURL:    "",
+	}
 
-		profile, err := decodeProfile(&resp.Body)
-		if err != nil {
-			return nil, &RequestError{
-				Detail:     "Could not retrieve profile",
-				Underlying: err,
-			}
+	if APIKey != "" {
+		client.webAPI = true
+		if err := client.getWebAPIProfile(ctx, id); err != nil {
+			return nil, err
 		}
-		client.Profile = *profile
-		client.baseURL = fmt.Sprintf(
-			"%s/%s/%d",
-			endpoints.Base,
-			endpoints.ID,
-			profile.SteamID64,
-		)
 	} else {
-		log.WithField("id", id).Debug("Attempting to use ID as community ID")
-		resp, err := http.Get(
-			buildURL(
-				fmt.Sprintf("%s/%s/%s", endpoints.Base, endpoints.Alias, id),
-				nil,
-			),
-		)
+		resp, err := endpoints.Get(ctx, client.HTTPClient, buildURL(profileURL(id), nil))
 		if err != nil {
 			return nil, &RequestError{
 				Detail:     "Could not perform request",
 				Underlying: err,
+				Class:      steamerr.ErrNetwork,
 			}
 		}
+		log.Debugf("Retrieving %s", resp.Request.URL)
 
-		profile, err := decodeProfile(&resp.Body)
+		profile, err := decodeProfile(id, &resp.Body)
 		if err != nil {
 			return nil, &RequestError{
 				Detail:     "Could not retrieve profile",
 				Underlying: err,
 			}
 		}
+		client.Profile = *profile
 		client.baseURL = fmt.Sprintf(
 			"%s/%s/%d",
-			endpoints.Base,
+			endpoints.Config.Community,
 			endpoints.ID,
 			profile.SteamID64,
 		)
-		client.Profile = *profile
 	}
 
-	if err := client.GetGames(); err != nil {
+	if err := client.GetGamesContext(ctx); err != nil {
 		return nil, &RequestError{
 			Detail:     "Could not retrieve profile's games",
 			Underlying: err,
@@ -129,51 +132,198 @@ func NewClient(id string) (*Client, error) {
 // triggered.
 func NewClientPre(p *objects.XMLProfile) *Client {
 	return &Client{
-		HTTPClient: &http.Client{
-			Timeout: RequestTimeout,
-		},
+		HTTPClient: endpoints.NewHTTPClient(RequestTimeout),
 		baseURL: fmt.Sprintf(
 			"%s/%s/%d",
-			endpoints.Base, endpoints.ID, p.SteamID64,
+			endpoints.Config.Community, endpoints.ID, p.SteamID64,
 		),
+		webAPI:  APIKey != "",
 		Profile: *p,
 	}
 }
 
-func (c *Client) get(path string, params map[string]string) (*http.Response, error) {
+// profileURL returns the community URL of the profile with the given ID, any
+// form understood by the id package is accepted, anything else is treated as
+// a community ID
+func profileURL(s string) string {
+	if x, err := id.New(s); err == nil {
+		log.WithField("id", s).Debug("Attempting to use ID as 64bit")
+		return fmt.Sprintf("%s/%s/%d", endpoints.Config.Community, endpoints.ID, x.Full.Get())
+	}
+	if name, ok := id.Vanity(s); ok {
+		s = name
+	}
+	log.WithField("id", s).Debug("Attempting to use ID as community ID")
+	return fmt.Sprintf("%s/%s/%s", endpoints.Config.Community, endpoints.Alias, s)
+}
+
+// Resolve returns the 64bit ID of the profile with the given community ID
+// (custom URL name), this works for private profiles as well
+func Resolve(name string) (uint64, error) {
+	return ResolveContext(context.Background(), name)
+}
+
+// ResolveContext is like Resolve, the request is cancelled once ctx is done
+func ResolveContext(ctx context.Context, name string) (uint64, error) {
+	if n, ok := id.Vanity(name); ok {
+		name = n
+	}
+
+	if APIKey != "" {
+		c := &Client{HTTPClient: endpoints.NewHTTPClient(RequestTimeout), webAPI: true}
+		id64, err := c.resolveVanity(ctx, name)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(id64, 10, 64)
+	}
+
+	c := endpoints.NewHTTPClient(RequestTimeout)
+	resp, err := endpoints.Get(ctx, c, buildURL(
+		fmt.Sprintf("%s/%s/%s", endpoints.Config.Community, endpoints.Alias, name),
+		nil,
+	))
+	if err != nil {
+		return 0, &RequestError{
+			Detail:     "Could not perform request",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, &RequestError{
+			Detail:     "Could not read response",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+
+	if e := responseError(b); e != "" {
+		return 0, &ProfileError{ID: name, Err: ErrProfileNotFound}
+	}
+	var profile objects.XMLProfile
+	if err := xml.Unmarshal(b, &profile); err != nil {
+		return 0, &RequestError{
+			Detail:     "Could not decode XML",
+			Underlying: err,
+			Class:      steamerr.ErrDecode,
+		}
+	}
+	if profile.SteamID64 == 0 {
+		return 0, &RequestError{Detail: "Could not decode profile", Class: steamerr.ErrDecode}
+	}
+	return uint64(profile.SteamID64), nil
+}
+
+func (c *Client) get(ctx context.Context, path string, params map[string]string) (*http.Response, error) {
 	url := buildURL(
 		fmt.Sprintf("%s/%s", c.baseURL, path),
 		params,
 	)
 	log.WithField("url", url).Debug("Built URL")
 
-	return c.HTTPClient.Get(url)
+	return endpoints.Get(ctx, c.HTTPClient, url)
 }
 
-func decodeProfile(stream *io.ReadCloser) (*objects.XMLProfile, error) {
+func decodeProfile(id string, stream *io.ReadCloser) (*objects.XMLProfile, error) {
+	defer (*stream).Close()
+	b, err := ioutil.ReadAll(*stream)
+	if err != nil {
+		return nil, &RequestError{
+			Detail:     "Could not read response",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+
+	if e := responseError(b); e != "" {
+		log.WithFields(log.Fields{
+			"id":    id,
+			"error": e,
+		}).Debug("Profile request returned an error")
+		return nil, &ProfileError{ID: id, Err: ErrProfileNotFound}
+	}
+
 	var profile objects.XMLProfile
-	decoder := xml.NewDecoder(*stream)
-	err := decoder.Decode(&profile)
+	err = xml.Unmarshal(b, &profile)
 	if err != nil {
 		return nil, &RequestError{
 			Detail:     "Could not decode XML",
 			Underlying: err,
+			Class:      steamerr.ErrDecode,
 		}
 	}
 
-	if profile.SteamID64 != 0 {
-		profile.Complete()
-		log.Debugf("Decoded profile: %#v", profile)
-		return &profile, nil
+	if profile.SteamID64 == 0 {
+		return nil, &RequestError{Detail: "Could not decode profile", Class: steamerr.ErrDecode}
+	}
+
+	if (profile.Privacy != "" && profile.Privacy != "public") ||
+		(profile.PrivacyState != 0 && profile.PrivacyState != objects.VisibilityPublic) {
+		return nil, &ProfileError{ID: id, Err: ErrProfilePrivate}
+	}
+
+	if err := profile.Complete(); err != nil {
+		log.WithFields(log.Fields{
+			"id":  id,
+			"err": err,
+		}).Warn("Could not complete profile")
+	}
+	log.Debugf("Decoded profile: %#v", profile)
+	return &profile, nil
+}
+
+// responseError returns the message of an <error> response, an empty string
+// if the response isn't one
+func responseError(b []byte) string {
+	var errxml objects.XMLProfileError
+	if err := xml.Unmarshal(b, &errxml); err != nil {
+		return ""
 	}
+	return strings.TrimSpace(errxml.Error)
+}
+
+// Unwrap returns the underlying error
+func (e *RequestError) Unwrap() error {
+	return e.Underlying
+}
 
-	return nil, &RequestError{Detail: "Could not decode profile"}
+// Is reports whether target is the steamerr class of the error
+func (e *RequestError) Is(target error) bool {
+	return (e.Class != nil) && (target == e.Class)
+}
+
+func (e *ProfileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.ID, e.Err)
+}
+
+// Unwrap returns the reason the profile cannot be used
+func (e *ProfileError) Unwrap() error {
+	return e.Err
 }
 
 func (e *RequestError) Error() string {
+	if e.Underlying == nil {
+		return fmt.Sprintf("RequestError: %s", e.Detail)
+	}
 	return fmt.Sprintf(
 		"RequestError: %s | Underlying: %s",
 		e.Detail,
 		e.Underlying,
 	)
 }
+
+// statusClass returns the steamerr class of an unsuccessful HTTP status
+func statusClass(code int) error {
+	switch code {
+	case http.StatusTooManyRequests:
+		return steamerr.ErrRateLimited
+	case http.StatusNotFound:
+		return steamerr.ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return steamerr.ErrInvalid // The API key was rejected
+	}
+	return steamerr.ErrNetwork
+}
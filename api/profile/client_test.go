@@ -0,0 +1,87 @@
+package profile
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab.com/vultour/steamcli/steamerr"
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+func TestNewClientGames(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddProfile(&steamtest.Profile{
+		SteamID64: 76561197960287930,
+		Name:      "owner",
+		Games:     []steamtest.Game{{AppID: 10, Name: "Counter-Strike", HoursTotal: 1.5}},
+	})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287931, Name: "no games"})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287932, Name: "hidden", GamesPrivate: true})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287933, Name: "private", Private: true})
+
+	tests := []struct {
+		id    string
+		games int
+		err   error
+	}{
+		{"76561197960287930", 1, nil},
+		{"76561197960287931", 0, nil},
+		{"76561197960287932", 0, ErrGamesPrivate},
+		{"76561197960287933", 0, ErrProfilePrivate},
+		{"76561197960287934", 0, ErrProfileNotFound},
+	}
+	for _, tt := range tests {
+		c, err := NewClient(tt.id)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: NewClient() error = %v, want %v", tt.id, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(c.Profile.Games) != tt.games {
+			t.Errorf("%s: got %d games, want %d", tt.id, len(c.Profile.Games), tt.games)
+		}
+	}
+}
+
+func TestNewClientRateLimited(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287930, Name: "player"})
+	s.Limiter.MaxRetries = 2
+
+	// Retried until the server stops limiting
+	s.SetRateLimited(2)
+	if _, err := NewClient("76561197960287930"); err != nil {
+		t.Fatalf("NewClient() = %v after being rate limited twice", err)
+	}
+
+	s.SetRateLimited(100)
+	_, err := NewClient("76561197960287930")
+	if !errors.Is(err, steamerr.ErrRateLimited) {
+		t.Errorf("NewClient() error = %v, want rate limited", err)
+	}
+}
+
+func TestNewClientSlowResponse(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287930, Name: "player"})
+	s.Limiter.MaxRetries = 1
+	s.SetDelay(time.Second)
+
+	old := RequestTimeout
+	defer func() { RequestTimeout = old }()
+	RequestTimeout = 20 * time.Millisecond
+
+	_, err := NewClient("76561197960287930")
+	if !errors.Is(err, steamerr.ErrNetwork) {
+		t.Errorf("NewClient() error = %v, want a network error", err)
+	}
+}
@@ -1,12 +1,16 @@
 package profile
 
 import (
+	"context"
 	"encoding/xml"
-	"io"
-	"strings"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
 
 	"gitlab.com/vultour/steamcli/api/endpoints"
 	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -16,14 +20,29 @@ var MaxGamesPerRequest = 10
 
 // GetProfile retrieves the profile associated with the client
 func (c *Client) GetProfile() (*objects.XMLProfile, error) {
-	response, err := c.get("", nil)
+	return c.GetProfileContext(context.Background())
+}
+
+// GetProfileContext is like GetProfile, the request is cancelled once ctx is
+// done
+func (c *Client) GetProfileContext(ctx context.Context) (*objects.XMLProfile, error) {
+	if c.webAPI {
+		p := *c
+		if err := p.getWebAPIProfile(ctx, strconv.FormatInt(c.Profile.SteamID64, 10)); err != nil {
+			return &objects.XMLProfile{}, err
+		}
+		return &p.Profile, nil
+	}
+
+	response, err := c.get(ctx, "", nil)
 	if err != nil {
 		return &objects.XMLProfile{}, &RequestError{
 			Detail:     "Could not perform request",
 			Underlying: err,
+			Class:      steamerr.ErrNetwork,
 		}
 	}
-	profile, err := decodeProfile(&response.Body)
+	profile, err := decodeProfile(strconv.FormatInt(c.Profile.SteamID64, 10), &response.Body)
 	if err != nil {
 		return &objects.XMLProfile{}, &RequestError{
 			Detail:     "Could not retrieve profile",
@@ -35,32 +54,60 @@ func (c *Client) GetProfile() (*objects.XMLProfile, error) {
 
 // GetGames retrieves the user's game activity
 func (c *Client) GetGames() error {
-	response, err := c.get(endpoints.Games, map[string]string{"tab": "all"})
+	return c.GetGamesContext(context.Background())
+}
+
+// GetGamesContext is like GetGames, the request is cancelled once ctx is done
+func (c *Client) GetGamesContext(ctx context.Context) error {
+	if c.webAPI {
+		return c.getWebAPIGames(ctx)
+	}
+
+	response, err := c.get(ctx, endpoints.Games, map[string]string{"tab": "all"})
 	if err != nil {
 		return &RequestError{
 			Detail:     "Could not perform request",
 			Underlying: err,
+			Class:      steamerr.ErrNetwork,
 		}
 	}
 	defer response.Body.Close()
 
-	//if !profileExists(&response.Body) {
-	//	return &api.SteamProfileGames{}, &RequestError{
-	//		Detail: "The specified profile does not exist",
-	//	}
-	//}
+	b, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return &RequestError{
+			Detail:     "Could not read response",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+
+	id := strconv.FormatInt(c.Profile.SteamID64, 10)
+	if e := responseError(b); e != "" {
+		log.WithFields(log.Fields{
+			"id":    id,
+			"error": e,
+		}).Debug("Games request returned an error")
+		return &ProfileError{ID: id, Err: ErrGamesPrivate}
+	}
 
 	var games objects.XMLProfileGames
-	decoder := xml.NewDecoder(response.Body)
-	err = decoder.Decode(&games)
+	err = xml.Unmarshal(b, &games)
 	if err != nil {
 		return &RequestError{
 			Detail:     "Could not decode XML",
 			Underlying: err,
+			Class:      steamerr.ErrDecode,
 		}
 	}
 	log.WithField("games", len(games.Games)).Debug("Retrieved profile games")
 
+	// Private game details usually come back as an error, an empty list is
+	// only private if the profile isn't public
+	if (len(games.Games) < 1) && (c.Profile.PrivacyState != objects.VisibilityPublic) {
+		return &ProfileError{ID: id, Err: ErrGamesPrivate}
+	}
+
 	for id := range games.Games {
 		log.Debugf("Adding game to profile: %#v", games.Games[id])
 		c.Profile.Games[games.Games[id].AppID] = &games.Games[id]
@@ -69,14 +116,164 @@ func (c *Client) GetGames() error {
 	return nil
 }
 
-func profileExists(r *io.ReadCloser) bool {
-	var errxml objects.XMLProfileError
-	decoder := xml.NewDecoder(*r)
-	err := decoder.Decode(&errxml)
-	if err == nil {
-		if strings.TrimSpace(errxml.Error) == "" {
-			return true
+// GetAchievements retrieves the user's achievement progress in the game
+// The result is also stored in the client's profile, including stats that
+// aren't available so they aren't requested again.
+func (c *Client) GetAchievements(appid int) (*objects.XMLPlayerStats, error) {
+	return c.GetAchievementsContext(context.Background(), appid)
+}
+
+// GetAchievementsContext is like GetAchievements, the request is cancelled
+// once ctx is done
+func (c *Client) GetAchievementsContext(ctx context.Context, appid int) (*objects.XMLPlayerStats, error) {
+	response, err := c.get(
+		ctx,
+		fmt.Sprintf("%s/%d/", endpoints.Stats, appid),
+		map[string]string{"tab": "achievements"},
+	)
+	if err != nil {
+		return nil, &RequestError{
+			Detail:     "Could not perform request",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+	defer response.Body.Close()
+
+	b, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, &RequestError{
+			Detail:     "Could not read response",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
 		}
 	}
-	return false
+
+	id := strconv.FormatInt(c.Profile.SteamID64, 10)
+	if e := responseError(b); e != "" {
+		log.WithFields(log.Fields{
+			"id":    id,
+			"appid": appid,
+			"error": e,
+		}).Debug("Stats request returned an error")
+		c.setAchievements(&objects.XMLPlayerStats{
+			AppID:       appid,
+			Updated:     time.Now(),
+			Unavailable: true,
+		})
+		return nil, &ProfileError{ID: id, Err: ErrStatsNotFound}
+	}
+
+	var stats objects.XMLPlayerStats
+	if err := xml.Unmarshal(b, &stats); err != nil {
+		return nil, &RequestError{
+			Detail:     "Could not decode XML",
+			Underlying: err,
+			Class:      steamerr.ErrDecode,
+		}
+	}
+	stats.AppID = appid
+	stats.Updated = time.Now()
+	log.WithFields(log.Fields{
+		"appid":        appid,
+		"achievements": len(stats.Achievements),
+	}).Debug("Retrieved profile achievements")
+
+	c.setAchievements(&stats)
+	return &stats, nil
+}
+
+// setAchievements stores the stats in the client's profile
+func (c *Client) setAchievements(stats *objects.XMLPlayerStats) {
+	if c.Profile.Achievements == nil {
+		c.Profile.Achievements = make(map[int]*objects.XMLPlayerStats)
+	}
+	c.Profile.Achievements[stats.AppID] = stats
+}
+
+// GetFriends retrieves the user's public friends list
+func (c *Client) GetFriends() error {
+	return c.GetFriendsContext(context.Background())
+}
+
+// GetFriendsContext is like GetFriends, the request is cancelled once ctx is
+// done
+func (c *Client) GetFriendsContext(ctx context.Context) error {
+	response, err := c.get(ctx, endpoints.Friends, nil)
+	if err != nil {
+		return &RequestError{
+			Detail:     "Could not perform request",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+	defer response.Body.Close()
+
+	b, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return &RequestError{
+			Detail:     "Could not read response",
+			Underlying: err,
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+
+	if e := responseError(b); e != "" {
+		id := strconv.FormatInt(c.Profile.SteamID64, 10)
+		log.WithFields(log.Fields{
+			"id":    id,
+			"error": e,
+		}).Debug("Friends request returned an error")
+		return &ProfileError{ID: id, Err: ErrFriendsPrivate}
+	}
+
+	var friends objects.XMLProfileFriends
+	err = xml.Unmarshal(b, &friends)
+	if err != nil {
+		return &RequestError{
+			Detail:     "Could not decode friends list",
+			Underlying: err,
+			Class:      steamerr.ErrDecode,
+		}
+	}
+	log.WithField("friends", len(friends.Friends)).Debug("Retrieved profile friends")
+
+	c.Profile.Friends = friends.Friends
+	if c.Profile.Friends == nil {
+		c.Profile.Friends = make([]int64, 0)
+	}
+	return nil
+}
+
+// GetWishlist retrieves the user's wishlist
+// Private wishlists can't be told apart from empty ones, both come back empty.
+func (c *Client) GetWishlist() error {
+	return c.GetWishlistContext(context.Background())
+}
+
+// GetWishlistContext is like GetWishlist, the request is cancelled once ctx is
+// done
+func (c *Client) GetWishlistContext(ctx context.Context) error {
+	obj := struct {
+		Response struct {
+			Items []objects.JSONWishlistItem `json:"items"`
+		} `json:"response"`
+	}{}
+	err := c.webAPIGet(
+		ctx,
+		endpoints.GetWishlist,
+		map[string]string{"steamid": strconv.FormatInt(c.Profile.SteamID64, 10)},
+		&obj,
+	)
+	if err != nil {
+		return err
+	}
+	log.WithField("games", len(obj.Response.Items)).Debug("Retrieved profile wishlist")
+
+	wishlist := make(objects.JSONWishlist)
+	for i := range obj.Response.Items {
+		wishlist[obj.Response.Items[i].AppID] = &obj.Response.Items[i]
+	}
+	c.Profile.Wishlist = wishlist
+	return nil
 }
@@ -0,0 +1,85 @@
+package profile
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+func TestGetWishlist(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287930, Name: "public", Wishlist: []int{10, 20}})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287931, Name: "private", Private: true, Wishlist: []int{10}})
+
+	tests := []struct {
+		id   int64
+		want []int
+	}{
+		{76561197960287930, []int{10, 20}},
+		{76561197960287931, nil},
+		{76561197960287932, nil}, // Unknown
+	}
+	for _, tt := range tests {
+		c := NewClientPre(&objects.XMLProfile{SteamID64: tt.id})
+		if err := c.GetWishlist(); err != nil {
+			t.Fatalf("%d: GetWishlist() = %v", tt.id, err)
+		}
+		if c.Profile.Wishlist == nil {
+			t.Fatalf("%d: wishlist is nil after fetching it", tt.id)
+		}
+		if len(c.Profile.Wishlist) != len(tt.want) {
+			t.Fatalf("%d: got %d wishlist items, want %d", tt.id, len(c.Profile.Wishlist), len(tt.want))
+		}
+		for i, appid := range tt.want {
+			item, ok := c.Profile.Wishlist[appid]
+			if !ok {
+				t.Fatalf("%d: %d missing from wishlist", tt.id, appid)
+			}
+			if (item.AppID != appid) || (item.Priority != i+1) || (item.Added == 0) {
+				t.Errorf("%d: unexpected wishlist item %+v", tt.id, item)
+			}
+		}
+	}
+}
+
+func TestGetFriends(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287930, Name: "public", Friends: []int64{76561197960287931}})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287931, Name: "hidden", FriendsPrivate: true})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287932, Name: "lonely"})
+
+	tests := []struct {
+		id      int64
+		friends []int64
+		err     error
+	}{
+		{76561197960287930, []int64{76561197960287931}, nil},
+		{76561197960287931, nil, ErrFriendsPrivate},
+		{76561197960287932, []int64{}, nil},
+	}
+	for _, tt := range tests {
+		c := NewClientPre(&objects.XMLProfile{SteamID64: tt.id})
+		err := c.GetFriends()
+		if tt.err != nil {
+			var perr *ProfileError
+			if !errors.As(err, &perr) || !errors.Is(err, tt.err) || !errors.Is(err, steamerr.ErrPrivate) {
+				t.Errorf("%d: GetFriends() = %#v, want %v", tt.id, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: GetFriends() = %v", tt.id, err)
+		}
+		if !reflect.DeepEqual(c.Profile.Friends, tt.friends) {
+			t.Errorf("%d: friends = %v, want %v", tt.id, c.Profile.Friends, tt.friends)
+		}
+	}
+}
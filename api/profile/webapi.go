@@ -0,0 +1,236 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	steamid "gitlab.com/vultour/steamcli/id"
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// webAPIPlayer is a single player returned by GetPlayerSummaries
+type webAPIPlayer struct {
+	SteamID        string `json:"steamid"`
+	Visibility     int    `json:"communityvisibilitystate"`
+	PersonaName    string `json:"personaname"`
+	ProfileURL     string `json:"profileurl"`
+	TimeCreated    int64  `json:"timecreated"`
+	LocCountryCode string `json:"loccountrycode"`
+}
+
+// webAPIGame is a single game returned by GetOwnedGames
+type webAPIGame struct {
+	AppID           int    `json:"appid"`
+	Name            string `json:"name"`
+	PlaytimeForever int    `json:"playtime_forever"` // Minutes
+	Playtime2Weeks  int    `json:"playtime_2weeks"`  // Minutes
+}
+
+// webAPIGet performs a Web API request and decodes the JSON response into v
+func (c *Client) webAPIGet(ctx context.Context, method string, params map[string]string, v interface{}) error {
+	values := make(url.Values)
+	if APIKey != "" { // Only some methods need it
+		values.Set("key", APIKey)
+	}
+	values.Set("format", "json")
+	for k, p := range params {
+		values.Set(k, p)
+	}
+	u := fmt.Sprintf("%s/%s/?%s", endpoints.Config.WebAPI, method, values.Encode())
+	log.WithField("method", method).Debug("Performing Web API request")
+
+	response, err := endpoints.Get(ctx, c.HTTPClient, u)
+	if err != nil {
+		return &RequestError{
+			Detail:     "Could not perform request",
+			Underlying: redactKey(err),
+			Class:      steamerr.ErrNetwork,
+		}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != 200 {
+		return &RequestError{
+			Detail: fmt.Sprintf("Web API returned status %d", response.StatusCode),
+			Class:  statusClass(response.StatusCode),
+		}
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return &RequestError{
+			Detail:     "Could not decode JSON",
+			Underlying: err,
+			Class:      steamerr.ErrDecode,
+		}
+	}
+	return nil
+}
+
+// redactKey hides the API key in the URL of a *url.Error, its message
+// contains the whole URL
+func redactKey(err error) error {
+	var uerr *url.Error
+	if !errors.As(err, &uerr) {
+		return err
+	}
+	u, perr := url.Parse(uerr.URL)
+	if (perr != nil) || (u.Query().Get("key") == "") {
+		return err
+	}
+	q := u.Query()
+	q.Set("key", "REDACTED")
+	u.RawQuery = q.Encode()
+	uerr.URL = u.String()
+	return err
+}
+
+// resolveVanity returns the 64bit ID of the profile with the custom URL name
+func (c *Client) resolveVanity(ctx context.Context, name string) (string, error) {
+	obj := struct {
+		Response struct {
+			SteamID string `json:"steamid"`
+			Success int    `json:"success"`
+		} `json:"response"`
+	}{}
+	err := c.webAPIGet(ctx, endpoints.ResolveVanityURL, map[string]string{"vanityurl": name}, &obj)
+	if err != nil {
+		return "", err
+	}
+	if obj.Response.Success != 1 {
+		return "", &ProfileError{ID: name, Err: ErrProfileNotFound}
+	}
+	return obj.Response.SteamID, nil
+}
+
+// getWebAPIProfile retrieves the profile using the Web API
+func (c *Client) getWebAPIProfile(ctx context.Context, id string) error {
+	var id64 string
+	if x, err := steamid.New(id); err == nil {
+		id64 = strconv.FormatUint(x.Full.Get(), 10)
+	} else {
+		name := id
+		if n, ok := steamid.Vanity(id); ok {
+			name = n
+		}
+		log.WithField("id", name).Debug("Resolving community ID")
+		if id64, err = c.resolveVanity(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	obj := struct {
+		Response struct {
+			Players []webAPIPlayer `json:"players"`
+		} `json:"response"`
+	}{}
+	err := c.webAPIGet(ctx, endpoints.GetPlayerSummaries, map[string]string{"steamids": id64}, &obj)
+	if err != nil {
+		return err
+	}
+	if len(obj.Response.Players) < 1 {
+		return &ProfileError{ID: id, Err: ErrProfileNotFound}
+	}
+
+	player := obj.Response.Players[0]
+	if player.Visibility != objects.VisibilityPublic {
+		return &ProfileError{ID: id, Err: ErrProfilePrivate}
+	}
+
+	profile := objects.XMLProfile{
+		SteamID:      player.PersonaName,
+		Privacy:      "public",
+		PrivacyState: player.Visibility,
+		Location:     player.LocCountryCode,
+		MemberSince:  time.Unix(player.TimeCreated, 0),
+	}
+	profile.SteamID64, err = strconv.ParseInt(player.SteamID, 10, 64)
+	if err != nil {
+		return &RequestError{
+			Detail:     "Could not decode profile",
+			Underlying: err,
+			Class:      steamerr.ErrDecode,
+		}
+	}
+	if parts := strings.Split(strings.Trim(player.ProfileURL, "/"), "/"); len(parts) > 1 {
+		if parts[len(parts)-2] == endpoints.Alias {
+			profile.CustomURL = parts[len(parts)-1]
+		}
+	}
+	if err := profile.Complete(); err != nil {
+		log.WithFields(log.Fields{
+			"id":  id,
+			"err": err,
+		}).Warn("Could not complete profile")
+	}
+	log.Debugf("Decoded profile: %#v", profile)
+
+	c.Profile = profile
+	c.baseURL = fmt.Sprintf(
+		"%s/%s/%d",
+		endpoints.Config.Community,
+		endpoints.ID,
+		profile.SteamID64,
+	)
+	return nil
+}
+
+// getWebAPIGames retrieves the user's games using the Web API
+func (c *Client) getWebAPIGames(ctx context.Context) error {
+	obj := struct {
+		Response struct {
+			GameCount *int         `json:"game_count"` // Missing if private
+			Games     []webAPIGame `json:"games"`
+		} `json:"response"`
+	}{}
+	err := c.webAPIGet(
+		ctx,
+		endpoints.GetOwnedGames,
+		map[string]string{
+			"steamid":                   strconv.FormatInt(c.Profile.SteamID64, 10),
+			"include_appinfo":           "1",
+			"include_played_free_games": "1",
+		},
+		&obj,
+	)
+	if err != nil {
+		return err
+	}
+	log.WithField("games", len(obj.Response.Games)).Debug("Retrieved profile games")
+
+	// Private game details come back as an empty response, accounts without
+	// games still have a game count
+	if obj.Response.GameCount == nil {
+		return &ProfileError{
+			ID:  strconv.FormatInt(c.Profile.SteamID64, 10),
+			Err: ErrGamesPrivate,
+		}
+	}
+
+	for _, g := range obj.Response.Games {
+		game := &objects.XMLProfileGame{
+			Name:          g.Name,
+			AppID:         g.AppID,
+			PlaytimeTotal: formatHours(g.PlaytimeForever),
+		}
+		if g.Playtime2Weeks > 0 {
+			game.PlaytimeTwoWeeks = formatHours(g.Playtime2Weeks)
+		}
+		c.Profile.Games[g.AppID] = game
+	}
+	return nil
+}
+
+// formatHours formats minutes the same way as the community XML formats hours
+func formatHours(minutes int) string {
+	return strconv.FormatFloat(float64(minutes)/60, 'f', 1, 64)
+}
@@ -0,0 +1,99 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/steamerr"
+	"gitlab.com/vultour/steamcli/steamtest"
+)
+
+func TestWebAPIGetRedactsKey(t *testing.T) {
+	oldKey, oldConfig := APIKey, endpoints.Config
+	defer func() {
+		APIKey, endpoints.Config = oldKey, oldConfig
+	}()
+	APIKey = "SECRETKEY123"
+	endpoints.Config = endpoints.Default()
+	endpoints.Config.Limiter = nil
+	endpoints.Config.WebAPI = "http://127.0.0.1:1" // Nothing listens there
+
+	c := &Client{HTTPClient: endpoints.NewHTTPClient(RequestTimeout)}
+	err := c.webAPIGet(context.Background(), endpoints.GetOwnedGames, nil, &struct{}{})
+	if err == nil {
+		t.Fatal("webAPIGet() succeeded without a server")
+	}
+	if strings.Contains(err.Error(), APIKey) {
+		t.Errorf("error contains the API key: %s", err)
+	}
+	if !strings.Contains(err.Error(), "key=REDACTED") {
+		t.Errorf("error doesn't show the redacted key: %s", err)
+	}
+}
+
+func TestWebAPIClient(t *testing.T) {
+	s := steamtest.NewServer()
+	defer s.Close()
+	defer s.Use()()
+	defer func(old string) { APIKey = old }(APIKey)
+	APIKey = "SECRETKEY123"
+	since := time.Date(2003, time.September, 12, 0, 0, 0, 0, time.UTC)
+	s.AddProfile(&steamtest.Profile{
+		SteamID64:   76561197960287930,
+		Name:        "Rabscuttle",
+		CustomURL:   "gabelogannewell",
+		MemberSince: since,
+		Games: []steamtest.Game{
+			{AppID: 10, Name: "Counter-Strike", HoursTotal: 1.5, HoursTwoWeeks: 0.5},
+			{AppID: 20, Name: "Team Fortress Classic", HoursTotal: 2},
+		},
+	})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287931, Name: "private", Private: true})
+	s.AddProfile(&steamtest.Profile{SteamID64: 76561197960287932, Name: "hidden", GamesPrivate: true})
+
+	for _, id := range []string{"76561197960287930", "gabelogannewell", "https://steamcommunity.com/id/gabelogannewell/"} {
+		c, err := NewClient(id)
+		if err != nil {
+			t.Errorf("NewClient(%q) = %v", id, err)
+			continue
+		}
+		p := c.Profile
+		if (p.SteamID64 != 76561197960287930) || (p.SteamID != "Rabscuttle") ||
+			(p.CustomURL != "gabelogannewell") || !p.MemberSince.Equal(since) {
+			t.Errorf("NewClient(%q) profile = %+v", id, p)
+		}
+		if g, ok := p.Games.Contains(10); !ok || (g.Name != "Counter-Strike") ||
+			(g.PlaytimeTotal != "1.5") || (g.PlaytimeTwoWeeks != "0.5") {
+			t.Errorf("NewClient(%q) game 10 = %+v, %t", id, g, ok)
+		}
+		if len(p.Games) != 2 {
+			t.Errorf("NewClient(%q) has %d games, want 2", id, len(p.Games))
+		}
+	}
+
+	tests := []struct {
+		id  string
+		err error
+	}{
+		{"76561197960287931", ErrProfilePrivate},
+		{"76561197960287932", ErrGamesPrivate},
+		{"76561197960287933", ErrProfileNotFound},
+		{"nobody", ErrProfileNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := NewClient(tt.id); !errors.Is(err, tt.err) {
+			t.Errorf("NewClient(%q) = %v, want %v", tt.id, err, tt.err)
+		}
+	}
+
+	// A missing key is rejected by the methods that need one
+	APIKey = ""
+	c := &Client{HTTPClient: endpoints.NewHTTPClient(RequestTimeout)}
+	if _, err := c.resolveVanity(context.Background(), "gabelogannewell"); !errors.Is(err, steamerr.ErrInvalid) {
+		t.Errorf("resolveVanity() without a key = %v, want an invalid key", err)
+	}
+}
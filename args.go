@@ -4,7 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/vultour/steamcli/aggregator"
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/id"
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/ratelimit"
 
 	"github.com/akamensky/argparse"
 	log "github.com/sirupsen/logrus"
@@ -18,20 +27,85 @@ type Arguments struct { // .
 	Debug   *bool
 	JSONLog *bool
 
-	IDs           *[]string
-	CacheFile     *string
-	CacheParallel *int
-	NoAutoCache   *bool
+	IDs               *[]string
+	FriendsOf         *[]string
+	MaxFriends        *int
+	Groups            *[]string
+	MaxMembers        *int
+	MemberConcurrency *int
+	CacheFile         *string
+	CacheParallel     *int
+	Workers           *int
+	NoAutoCache       *bool
+	APIKey            *string
+	Country           *string
+	Language          *string
+	CommunityURL      *string
+	StoreURL          *string
+	WebAPIURL         *string
+	RateLimit         *[]string
+	MaxRetries        *int
+	NoProgress        *bool
+
+	// Autogenerated
+	RateLimits map[string]ratelimit.Limit
 
 	Games struct { // .games
+		Command      *argparse.Command
+		FetchTags    *bool
+		FetchReviews *bool
+
+		TagsOnly  *bool
+		Tag       *[]string
+		Common    *bool
+		MinOwners *int
+		And       *bool
+		Invalid   *bool
+
+		ReleasedAfter  *string
+		ReleasedBefore *string
+		MinMetacritic  *int
+		MinReviews     *int
+		Controller     *string
+		Genre          *[]string
+		Sort           *string
+		Reverse        *bool
+		Field          *[]string
+		IncludeDLC     *bool
+		OnlyDLC        *bool
+		Wishlist       *bool
+		WishlistedBy   *[]string
+
+		MinCompletion *string
+
+		// Autogenerated
+		Filter             objects.GameFilter
+		MinCompletionFloat float64
+	}
+
+	Recent struct { // .recent
 		Command   *argparse.Command
 		FetchTags *bool
 
-		TagsOnly *bool
-		Tag      *[]string
-		Common   *bool
-		And      *bool
-		Invalid  *bool
+		Tag     *[]string
+		And     *bool
+		Invalid *bool
+		Field   *[]string
+	}
+
+	Achievements struct { // .achievements
+		Command *argparse.Command
+
+		AppID   *int
+		Missing *bool
+	}
+
+	ID struct { // .id
+		Command *argparse.Command
+
+		JSON    *bool
+		Resolve *bool
+		Values  []string // Given right after the command
 	}
 
 	Cache struct { // .cache
@@ -42,6 +116,7 @@ type Arguments struct { // .
 			PurgeInvalid     *argparse.Command // .cache.games.purge-invalid
 			PurgeMissingTags *argparse.Command // .cache.games.purge-misssing-tags
 			Info             *argparse.Command
+			Resume           *argparse.Command // .cache.games.resume
 
 			FetchTags *bool
 
@@ -145,7 +220,40 @@ func ParseArgs() *Arguments {
 	ap.IDs = ap.Parser.List(
 		"i", "id",
 		&argparse.Options{
-			Help: "A steam ID (64bit, STEAM_X:Y:Z, or community ID)",
+			Help: "A steam ID (64bit, STEAM_X:Y:Z, [U:1:Z], profile link, friend code, or community ID)",
+		},
+	)
+	ap.FriendsOf = ap.Parser.List(
+		"", "friends-of",
+		&argparse.Options{
+			Help: "Use this account and all of its friends with public profiles (can be used more than once)",
+		},
+	)
+	ap.MaxFriends = ap.Parser.Int(
+		"", "max-friends",
+		&argparse.Options{
+			Help:    "Maximum number of friends added by each --friends-of, 0 for no limit",
+			Default: aggregator.MaxFriends,
+		},
+	)
+	ap.Groups = ap.Parser.List(
+		"g", "group",
+		&argparse.Options{
+			Help: "Use members of this community group with public profiles, group name or 64bit ID (can be used more than once)",
+		},
+	)
+	ap.MaxMembers = ap.Parser.Int(
+		"", "max-members",
+		&argparse.Options{
+			Help:    "Maximum number of members added by each --group, 0 for no limit",
+			Default: aggregator.MaxMembers,
+		},
+	)
+	ap.MemberConcurrency = ap.Parser.Int(
+		"", "member-concurrency",
+		&argparse.Options{
+			Help:    "How many group member profiles to fetch at the same time",
+			Default: aggregator.MemberConcurrency,
 		},
 	)
 	ap.CacheFile = ap.Parser.String(
@@ -157,16 +265,78 @@ func ParseArgs() *Arguments {
 	ap.CacheParallel = ap.Parser.Int(
 		"", "cache-parallel",
 		&argparse.Options{
-			Help:    "How many games to fetch in parallel when getting details",
+			Help:    "How many games to fetch in one request when getting details",
 			Default: 1,
 		},
 	)
+	ap.Workers = ap.Parser.Int(
+		"", "workers",
+		&argparse.Options{
+			Help:    "How many store requests to make at the same time (rate limits still apply)",
+			Default: aggregator.Workers,
+		},
+	)
+	ap.NoProgress = ap.Parser.Flag(
+		"", "no-progress",
+		&argparse.Options{
+			Help: "Don't show a progress bar while updating the cache (it's only shown on a terminal)",
+		},
+	)
 	ap.NoAutoCache = ap.Parser.Flag(
 		"n", "no-auto-cache",
 		&argparse.Options{
 			Help: "Don't retrieve details for non-cached games",
 		},
 	)
+	ap.APIKey = ap.Parser.String(
+		"", "api-key",
+		&argparse.Options{
+			Help: "Steam Web API key, uses the official API instead of community XML (default: $STEAM_API_KEY)",
+		},
+	)
+	ap.Country = ap.Parser.String(
+		"", "country",
+		&argparse.Options{
+			Help: "Store region used for prices (two-letter country code, e.g. 'us' or 'de')",
+		},
+	)
+	ap.Language = ap.Parser.String(
+		"", "language",
+		&argparse.Options{
+			Help: "Store language used for names and descriptions (e.g. 'english' or 'german')",
+		},
+	)
+	ap.CommunityURL = ap.Parser.String(
+		"", "community-url",
+		&argparse.Options{
+			Help: "Root of the Steam Community, e.g. a mirror (default: $" + endpoints.EnvCommunity + " or " + endpoints.DefaultCommunity + ")",
+		},
+	)
+	ap.StoreURL = ap.Parser.String(
+		"", "store-url",
+		&argparse.Options{
+			Help: "Root of the Steam Store (default: $" + endpoints.EnvStore + " or " + endpoints.DefaultStore + ")",
+		},
+	)
+	ap.WebAPIURL = ap.Parser.String(
+		"", "webapi-url",
+		&argparse.Options{
+			Help: "Root of the Steam Web API (default: $" + endpoints.EnvWebAPI + " or " + endpoints.DefaultWebAPI + ")",
+		},
+	)
+	ap.RateLimit = ap.Parser.List(
+		"", "rate-limit",
+		&argparse.Options{
+			Help: "Request limit of a host as host=interval[/burst], e.g. store.steampowered.com=1s (can be used more than once)",
+		},
+	)
+	ap.MaxRetries = ap.Parser.Int(
+		"", "max-retries",
+		&argparse.Options{
+			Help:    "How many times a rate limited or failed request is retried",
+			Default: ratelimit.Default.MaxRetries,
+		},
+	)
 
 	// .games
 	ap.Games.Command = ap.Parser.NewCommand(
@@ -178,6 +348,12 @@ func ParseArgs() *Arguments {
 			Help: "Fetch game tags, requires additional HTTP request per game",
 		},
 	)
+	ap.Games.FetchReviews = ap.Games.Command.Flag(
+		"", "fetch-reviews",
+		&argparse.Options{
+			Help: "Fetch user review summaries, requires additional HTTP request per game",
+		},
+	)
 	ap.Games.TagsOnly = ap.Games.Command.Flag(
 		"", "tags-only",
 		&argparse.Options{
@@ -190,6 +366,12 @@ func ParseArgs() *Arguments {
 			Help: "Show only games common across all accounts",
 		},
 	)
+	ap.Games.MinOwners = ap.Games.Command.Int(
+		"", "min-owners",
+		&argparse.Options{
+			Help: "Show only games owned by at least this many accounts",
+		},
+	)
 	ap.Games.Tag = ap.Games.Command.List(
 		"t", "tag",
 		&argparse.Options{
@@ -208,6 +390,162 @@ func ParseArgs() *Arguments {
 			Help: "Also show invalid games (no store page for the App ID)",
 		},
 	)
+	ap.Games.ReleasedAfter = ap.Games.Command.String(
+		"", "released-after",
+		&argparse.Options{
+			Help: "Only select games released after this year or date (YYYY or YYYY-MM-DD)",
+		},
+	)
+	ap.Games.ReleasedBefore = ap.Games.Command.String(
+		"", "released-before",
+		&argparse.Options{
+			Help: "Only select games released before this year or date (YYYY or YYYY-MM-DD)",
+		},
+	)
+	ap.Games.MinMetacritic = ap.Games.Command.Int(
+		"", "min-metacritic",
+		&argparse.Options{
+			Help: "Only select games with at least this metacritic score",
+		},
+	)
+	ap.Games.MinReviews = ap.Games.Command.Int(
+		"", "min-review-percent",
+		&argparse.Options{
+			Help: "Only select games with at least this percentage of positive user reviews (requires --fetch-reviews at least once)",
+		},
+	)
+	ap.Games.Controller = ap.Games.Command.Selector(
+		"", "controller",
+		[]string{objects.ControllerFull, objects.ControllerPartial},
+		&argparse.Options{
+			Help: "Only select games with this level of controller support (partial includes full)",
+		},
+	)
+	ap.Games.Genre = ap.Games.Command.List(
+		"", "genre",
+		&argparse.Options{
+			Help: "Only select games in this genre (can be used more than once)",
+		},
+	)
+	ap.Games.IncludeDLC = ap.Games.Command.Flag(
+		"", "include-dlc",
+		&argparse.Options{
+			Help: "Show DLC and soundtracks under their base game, along with how many of them every account owns",
+		},
+	)
+	ap.Games.OnlyDLC = ap.Games.Command.Flag(
+		"", "only-dlc",
+		&argparse.Options{
+			Help: "Show only DLC and soundtracks",
+		},
+	)
+	ap.Games.MinCompletion = ap.Games.Command.String(
+		"", "min-completion",
+		&argparse.Options{
+			Help: "Only select games where every owner unlocked at least this percentage of achievements (e.g. 50%), requires an HTTP request per game and account",
+		},
+	)
+	ap.Games.Wishlist = ap.Games.Command.Flag(
+		"w", "wishlist",
+		&argparse.Options{
+			Help: "Select games on the accounts' wishlists instead of the ones they own",
+		},
+	)
+	ap.Games.WishlistedBy = ap.Games.Command.List(
+		"", "wishlisted-by",
+		&argparse.Options{
+			Help: "Only select games on this account's wishlist (can be used more than once)",
+		},
+	)
+	ap.Games.Sort = ap.Games.Command.Selector(
+		"s", "sort",
+		sortKeys(),
+		&argparse.Options{
+			Help: "Sort games by this key",
+		},
+	)
+	ap.Games.Reverse = ap.Games.Command.Flag(
+		"r", "reverse",
+		&argparse.Options{
+			Help: "Reverse the sort order",
+		},
+	)
+	ap.Games.Field = ap.Games.Command.List(
+		"", "field",
+		&argparse.Options{
+			Help: "Output field (can be used more than once), one of: " +
+				strings.Join(fieldNames(), ", "),
+		},
+	)
+
+	// .recent
+	ap.Recent.Command = ap.Parser.NewCommand(
+		"recent", "Show games played in the last two weeks",
+	)
+	ap.Recent.FetchTags = ap.Recent.Command.Flag(
+		"f", "fetch-tags",
+		&argparse.Options{
+			Help: "Fetch game tags, requires additional HTTP request per game",
+		},
+	)
+	ap.Recent.Tag = ap.Recent.Command.List(
+		"t", "tag",
+		&argparse.Options{
+			Help: "Only select games matching this tag (can be used more than once)",
+		},
+	)
+	ap.Recent.And = ap.Recent.Command.Flag(
+		"a", "and",
+		&argparse.Options{
+			Help: "Show games that match all of the specified tags, default is _any_ of the tags",
+		},
+	)
+	ap.Recent.Invalid = ap.Recent.Command.Flag(
+		"", "invalid",
+		&argparse.Options{
+			Help: "Also show invalid games (no store page for the App ID)",
+		},
+	)
+	ap.Recent.Field = ap.Recent.Command.List(
+		"", "field",
+		&argparse.Options{
+			Help: "Output field (can be used more than once), one of: " +
+				strings.Join(fieldNames(), ", "),
+		},
+	)
+
+	// .achievements
+	ap.Achievements.Command = ap.Parser.NewCommand(
+		"achievements", "Compare achievement progress in a game",
+	)
+	ap.Achievements.AppID = ap.Achievements.Command.Int(
+		"", "appid",
+		&argparse.Options{
+			Required: true,
+			Help:     "The App ID of the game",
+		},
+	)
+	ap.Achievements.Missing = ap.Achievements.Command.Flag(
+		"m", "missing",
+		&argparse.Options{
+			Help: "Only show achievements not unlocked by everyone",
+		},
+	)
+
+	// .id
+	ap.ID.Command = ap.Parser.NewCommand(
+		"id", "Convert and inspect the Steam IDs given after the command or by --id (or read from stdin)",
+	)
+	ap.ID.JSON = ap.ID.Command.Flag(
+		"", "json",
+		&argparse.Options{Help: "Print the result as JSON"},
+	)
+	ap.ID.Resolve = ap.ID.Command.Flag(
+		"", "resolve",
+		&argparse.Options{
+			Help: "Look up custom URL names over the network",
+		},
+	)
 
 	// .cache
 	ap.Cache.Command = ap.Parser.NewCommand(
@@ -243,6 +581,12 @@ func ParseArgs() *Arguments {
 		"Show information about the game cache",
 	)
 
+	// .cache.games.resume
+	ap.Cache.Games.Resume = ap.Cache.Games.Command.NewCommand(
+		"resume",
+		"Fetch the games left pending by an interrupted update",
+	)
+
 	// .cache.games.print
 	ap.Cache.Games.Print.Command = ap.Cache.Games.Command.NewCommand(
 		"print",
@@ -292,24 +636,28 @@ func ParseArgs() *Arguments {
 	)
 
 	// Parse
-	if err := ap.Parser.Parse(os.Args); err != nil {
+	args, values := splitValues(os.Args, "id")
+	ap.ID.Values = values
+	if err := ap.Parser.Parse(args); err != nil {
 		fmt.Print(ap.Parser.Usage(err))
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 	if err := validateArgs(ap); err != nil {
 		fmt.Print(ap.Parser.Usage(err))
-		os.Exit(2)
+		os.Exit(exitInvalid)
 	}
 	if err := complete(ap); err != nil {
 		fmt.Print(ap.Parser.Usage(err))
+		os.Exit(exitInvalid)
 	}
 
 	return ap
 }
 
 func validateArgs(a *Arguments) error {
-	if a.Games.Command.Happened() {
-		if len(*a.IDs) < 1 {
+	if a.Games.Command.Happened() || a.Achievements.Command.Happened() ||
+		a.Recent.Command.Happened() {
+		if (len(*a.IDs) < 1) && (len(*a.FriendsOf) < 1) && (len(*a.Groups) < 1) {
 			return errors.New("No Steam IDs specified")
 		}
 	}
@@ -320,6 +668,25 @@ func validateArgs(a *Arguments) error {
 		}
 	}
 
+	if a.Games.Command.Happened() {
+		if err := validateFields(*a.Games.Field); err != nil {
+			return err
+		}
+		if *a.Games.IncludeDLC && *a.Games.OnlyDLC {
+			return errors.New("--include-dlc and --only-dlc are mutually exclusive")
+		}
+	}
+
+	if a.Recent.Command.Happened() {
+		if err := validateFields(*a.Recent.Field); err != nil {
+			return err
+		}
+	}
+
+	if len(*a.Country) > 0 && len(*a.Country) != 2 {
+		return fmt.Errorf("Invalid country code: '%s'", *a.Country)
+	}
+
 	log.SetLevel(log.WarnLevel)
 	if *a.Verbose {
 		log.SetLevel(log.InfoLevel)
@@ -336,19 +703,64 @@ func validateArgs(a *Arguments) error {
 }
 
 func complete(a *Arguments) error {
+	if *a.APIKey == "" {
+		*a.APIKey = os.Getenv("STEAM_API_KEY")
+	}
+	a.RateLimits = make(map[string]ratelimit.Limit)
+	for _, v := range *a.RateLimit {
+		host, limit, err := parseRateLimit(v)
+		if err != nil {
+			return err
+		}
+		a.RateLimits[host] = limit
+	}
+
+	if !a.ID.Command.Happened() { // Inspects the IDs as given
+		normaliseIDs(*a.IDs)
+	}
+	normaliseIDs(*a.FriendsOf)
+	normaliseIDs(*a.Games.WishlistedBy)
+
+	if a.Games.Command.Happened() {
+		var err error
+		f := &a.Games.Filter
+		if f.ReleasedAfter, err = parseDate(*a.Games.ReleasedAfter, true); err != nil {
+			return err
+		}
+		if f.ReleasedBefore, err = parseDate(*a.Games.ReleasedBefore, false); err != nil {
+			return err
+		}
+		f.MinMetacritic = *a.Games.MinMetacritic
+		f.MinReviews = float64(*a.Games.MinReviews)
+		f.Controller = *a.Games.Controller
+		f.Genres = *a.Games.Genre
+		if *a.Games.MinCompletion != "" {
+			a.Games.MinCompletionFloat, err = strconv.ParseFloat(
+				strings.TrimSuffix(strings.TrimSpace(*a.Games.MinCompletion), "%"), 64,
+			)
+			if err != nil {
+				return fmt.Errorf("could not parse completion: '%s'", *a.Games.MinCompletion)
+			}
+		}
+		if *a.Games.IncludeDLC {
+			f.DLC = objects.DLCInclude
+		} else if *a.Games.OnlyDLC {
+			f.DLC = objects.DLCOnly
+		}
+	}
 	if a.Cache.Command.Happened() {
 		if a.Cache.Games.Command.Happened() {
 			if a.Cache.Games.Print.Command.Happened() {
 				appids, err := sliceToInt(*a.Cache.Games.Print.AppID)
 				if err != nil {
-					return fmt.Errorf("could not convert appid to number: %d", err)
+					return fmt.Errorf("could not convert appid to number: %s", err)
 				}
 				a.Cache.Games.Print.AppIDInt = appids
 			}
 			if a.Cache.Games.Delete.Command.Happened() {
 				appids, err := sliceToInt(*a.Cache.Games.Delete.AppID)
 				if err != nil {
-					return fmt.Errorf("could not convert appid to number: %d", err)
+					return fmt.Errorf("could not convert appid to number: %s", err)
 				}
 				a.Cache.Games.Delete.AppIDInt = appids
 			}
@@ -357,6 +769,92 @@ func complete(a *Arguments) error {
 	return nil
 }
 
+// splitValues removes the values given right after the command from args, up
+// to the first flag, argparse doesn't support positional arguments
+// Subcommands are only recognised as the first argument, so are the values.
+func splitValues(args []string, command string) ([]string, []string) {
+	if (len(args) < 2) || (args[1] != command) {
+		return args, nil
+	}
+	n := 2
+	for (n < len(args)) && !strings.HasPrefix(args[n], "-") {
+		n++
+	}
+	values := append([]string(nil), args[2:n]...)
+	return append(args[:2:2], args[n:]...), values
+}
+
+// normaliseIDs replaces every form of Steam ID understood by the id package
+// with the 64bit ID and links to custom URLs with the community ID
+// Anything else is left as is and treated as a community ID.
+func normaliseIDs(ids []string) {
+	for i, v := range ids {
+		if x, err := id.New(v); err == nil {
+			ids[i] = strconv.FormatUint(x.Full.Get(), 10)
+		} else if name, ok := id.Vanity(v); ok {
+			ids[i] = name
+		}
+	}
+}
+
+// parseRateLimit parses a host=interval[/burst] rate limit
+func parseRateLimit(s string) (string, ratelimit.Limit, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if (len(parts) != 2) || (parts[0] == "") {
+		return "", ratelimit.Limit{}, fmt.Errorf("could not parse rate limit: '%s'", s)
+	}
+	limit := ratelimit.Limit{Burst: 1}
+	value := strings.SplitN(parts[1], "/", 2)
+	every, err := time.ParseDuration(value[0])
+	if err != nil {
+		return "", ratelimit.Limit{}, fmt.Errorf("could not parse rate limit interval: '%s'", value[0])
+	}
+	limit.Every = every
+	if len(value) > 1 {
+		if limit.Burst, err = strconv.Atoi(value[1]); err != nil {
+			return "", ratelimit.Limit{}, fmt.Errorf("could not parse rate limit burst: '%s'", value[1])
+		}
+	}
+	return parts[0], limit, nil
+}
+
+// parseDate parses a year or a full date, a year is extended to its last day
+// if end is set so "after 2015" means 2016 onwards.
+func parseDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse date: '%s'", s)
+	}
+	if end {
+		return t.AddDate(1, 0, 0).Add(-time.Nanosecond), nil
+	}
+	return t, nil
+}
+
+func sortKeys() []string {
+	ret := make([]string, 0, len(objects.GameSortKeys))
+	for k := range objects.GameSortKeys {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func fieldNames() []string {
+	ret := make([]string, 0, len(gameFields))
+	for k := range gameFields {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
 func sliceToInt(source []string) ([]int, error) {
 	ret := make([]int, 0, len(source))
 	for _, x := range source {
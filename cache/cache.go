@@ -3,13 +3,17 @@ package cache
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/steamerr"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -19,17 +23,63 @@ import (
 // the call to New()
 var FileLocation = ""
 
+// The following errors describe why the cache file cannot be used
+// They're wrapped in a FileError, test for them using errors.Is.
+var (
+	ErrNoLocation = steamerr.New(steamerr.ErrCache, "cannot determine the cache location")
+	ErrUnreadable = steamerr.New(steamerr.ErrCache, "cannot read the cache file")
+	ErrCorrupt    = steamerr.New(steamerr.ErrCache, "cannot decode the cache file")
+)
+
+// FileError is returned when the cache file cannot be loaded
+type FileError struct {
+	Path  string
+	Err   error // One of the Err* values
+	Cause error // What went wrong, e.g. a *json.SyntaxError
+}
+
+func (e *FileError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", e.Err, e.Cause)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Err, e.Path, e.Cause)
+}
+
+// Is reports whether target is the reason the file cannot be used
+func (e *FileError) Is(target error) bool {
+	return errors.Is(e.Err, target)
+}
+
+// Unwrap returns the cause
+func (e *FileError) Unwrap() error {
+	return e.Cause
+}
+
 // The following Max*Age constants define the time after which an item should be
 // purged from the cache
 const (
 	MaxGameAge    = (time.Hour * 24) * 30
 	MaxProfileAge = (time.Hour * 12)
+	MaxReviewAge  = (time.Hour * 24) * 7
+	MaxGroupAge   = (time.Hour * 24)
 )
 
 // Cache implements the steamcli cache
 type Cache struct {
 	Games    GameCache    `json:"games"`
 	Profiles ProfileCache `json:"profiles"`
+	Groups   GroupCache   `json:"groups"`
+
+	// Pending are the App IDs of games queued for fetching
+	Pending []int `json:"pending,omitempty"`
+
+	// RWMutex guards the contents while they're updated from multiple
+	// goroutines, don't hold it when calling Save
+	sync.RWMutex
+	saveMu sync.Mutex // Serialises writes to the cache file
+
+	changes  int // Since the last save, see Checkpoint
+	lastSave time.Time
 }
 
 // GameCache contains game objects
@@ -38,91 +88,164 @@ type GameCache map[int]*objects.JSONGame
 // ProfileCache contains profile objects
 type ProfileCache []*objects.XMLProfile
 
-// New returns a newly initialised Cache
-func New() *Cache {
+// GroupCache contains group objects
+type GroupCache []*objects.XMLGroup
+
+// New returns a newly initialised Cache loaded from FileLocation
+// Returns a *FileError if the location cannot be determined or the file cannot
+// be loaded.
+func New() (*Cache, error) {
 	c := &Cache{
 		Games:    make(GameCache),
 		Profiles: make(ProfileCache, 0, 4),
+		Groups:   make(GroupCache, 0, 1),
 	}
 
 	if FileLocation == "" {
 		cacheDir, err := os.UserCacheDir()
 		if err != nil {
-			log.WithField("err", err).Panic(
-				"Cannot determine user's cache location",
-			)
+			return nil, &FileError{Err: ErrNoLocation, Cause: err}
 		}
 		FileLocation = filepath.Join(cacheDir, "steamcli-cache.json")
 		log.WithField("file", FileLocation).Debug("Set cache location")
 	}
-	c.Load()
+	if err := c.Load(); err != nil {
+		return nil, err
+	}
 	c.Profiles.PurgeExpired()
 	c.Games.PurgeExpired()
+	c.Groups.PurgeExpired()
 
-	return c
+	return c, nil
 }
 
 // Save writes the cache to the file pointed at by FileLocation
+// It's safe to call from multiple goroutines.
 func (c *Cache) Save() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.Lock()
 	log.WithFields(log.Fields{
 		"size-games": len(c.Games),
+		"changes":    c.changes,
 	}).Debug("Saving cache")
-	gc, err := os.OpenFile(
-		FileLocation,
-		os.O_RDWR|os.O_TRUNC|os.O_CREATE,
-		0644,
-	)
+	b, err := json.MarshalIndent(c, "", "  ")
+	c.changes, c.lastSave = 0, time.Now()
+	c.Unlock()
 	if err != nil {
 		log.Debugf("Error: %#v", err)
-		return fmt.Errorf("Couldn't open cache file: %s", err)
+		return steamerr.Wrap(steamerr.ErrCache, "Couldn't encode cache", err)
 	}
 
-	b, err := json.MarshalIndent(c, "", "  ")
+	// Written next to the cache and renamed over it, so the cache is never left
+	// half written when the process is killed
+	tmp, err := ioutil.TempFile(filepath.Dir(FileLocation), filepath.Base(FileLocation)+".*.tmp")
 	if err != nil {
 		log.Debugf("Error: %#v", err)
-		return fmt.Errorf("Couldn't encode cache: %s", err)
+		return steamerr.Wrap(steamerr.ErrCache, "Couldn't create cache file", err)
 	}
-
-	for len(b) > 0 {
-		n, err := gc.Write(b)
-		if err != nil {
-			log.Debugf("Error: %#v", err)
-			return fmt.Errorf("Couldn't write to cache file: %s", err)
-		}
-		log.WithField("bytes", n).Debug("Wrote data to cache file")
-		b = b[n:]
-		log.WithField("remaining", len(b)).Debug("Bytes remaining")
+	if err := writeFile(tmp, b); err != nil {
+		os.Remove(tmp.Name())
+		log.Debugf("Error: %#v", err)
+		return steamerr.Wrap(steamerr.ErrCache, "Couldn't write to cache file", err)
+	}
+	if err := os.Rename(tmp.Name(), FileLocation); err != nil {
+		os.Remove(tmp.Name())
+		log.Debugf("Error: %#v", err)
+		return steamerr.Wrap(steamerr.ErrCache, "Couldn't replace cache file", err)
 	}
 
 	log.Debug("Cache save done")
 	return nil
 }
 
-// Load reads the cache from the file pointed at by FileLocation
-// Panics in case of any error as that's pretty much a complete disaster for the
-// system.
-func (c *Cache) Load() error {
-	if _, err := os.Stat(FileLocation); os.IsNotExist(err) {
-		c.Save() // Create the file if it's empty
+// writeFile writes b to the file, syncs it to disk, and closes it
+func writeFile(f *os.File, b []byte) error {
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	log.WithField("bytes", len(b)).Debug("Wrote data to cache file")
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// StoreGame adds the game to the cache, keeping the data of the cached copy
+// that isn't part of the store details (see objects.JSONGame.Merge)
+// It's safe to call from multiple goroutines.
+func (c *Cache) StoreGame(appid int, game *objects.JSONGame) {
+	c.Lock()
+	defer c.Unlock()
+	if old, cached := c.Games.Get(appid); cached && (old != game) {
+		game.Merge(old)
 	}
+	c.Games.Add(appid, game)
+	c.changes++
+}
+
+// StoreUnavailable records that the store doesn't sell a cached game in the
+// region, the rest of the cached details are kept
+// Returns false if the game isn't cached or is invalid, store it using
+// StoreGame then. It's safe to call from multiple goroutines.
+func (c *Cache) StoreUnavailable(appid int, country string) bool {
+	c.Lock()
+	defer c.Unlock()
+	g, cached := c.Games.Get(appid)
+	if !cached || g.Invalid {
+		return false
+	}
+	if g.Prices == nil {
+		g.Prices = make(map[string]*objects.JSONGamePrice)
+	}
+	g.Prices[strings.ToLower(country)] = nil
+	c.changes++
+	return true
+}
 
-	gc, err := os.OpenFile(FileLocation, os.O_RDONLY|os.O_CREATE, 0644)
+// Changed records a change made to a cached object while holding the lock,
+// see Checkpoint
+func (c *Cache) Changed() {
+	c.changes++
+}
+
+// Load reads the cache from the file pointed at by FileLocation, a missing or
+// empty file leaves the cache empty
+// Returns a *FileError if the file cannot be read or decoded, the cache may be
+// partially filled in that case.
+func (c *Cache) Load() error {
+	gc, err := os.Open(FileLocation)
+	if os.IsNotExist(err) {
+		log.WithField("file", FileLocation).Info("No cache file yet")
+		return nil
+	}
 	if err != nil {
 		log.Debugf("Error: %#v", err)
-		panic(fmt.Sprintf("Couldn't open cache file: %s", err))
+		return &FileError{Path: FileLocation, Err: ErrUnreadable, Cause: err}
 	}
+	defer gc.Close()
 
 	var b []byte
 	b, err = ioutil.ReadAll(gc)
 	if err != nil {
 		log.Debugf("Error: %#v", err)
-		panic(fmt.Sprintf("Couldn't read cache file: %s", err))
+		return &FileError{Path: FileLocation, Err: ErrUnreadable, Cause: err}
+	}
+	if len(b) < 1 {
+		return nil
 	}
 
 	err = json.Unmarshal(b, c)
 	if err != nil {
 		log.Debugf("Error: %#v", err)
-		panic(fmt.Sprintf("Couldn't decode cache file: %s", err))
+		return &FileError{Path: FileLocation, Err: ErrCorrupt, Cause: err}
 	}
 
 	log.WithFields(log.Fields{
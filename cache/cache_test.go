@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	FileLocation = filepath.Join(dir, "cache.json")
+	if err := ioutil.WriteFile(FileLocation, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New()
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	c.AddPending([]int{30, 10, 20})
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(files) != 1) || (files[0].Name() != "cache.json") {
+		t.Errorf("files after saving: %v, want only the cache", files)
+	}
+
+	loaded, err := New()
+	if err != nil {
+		t.Fatalf("New() after saving = %v", err)
+	}
+	if pending := loaded.PendingGames(); !reflect.DeepEqual(pending, []int{10, 20, 30}) {
+		t.Errorf("pending games after loading = %v", pending)
+	}
+}
@@ -217,6 +217,23 @@ func (g *GameCache) AllTags() []string {
 	return ret
 }
 
+// ReviewsExpired determines whether the game's review summary needs refetching
+func ReviewsExpired(g *objects.JSONGame) bool {
+	return (g.Reviews == nil) || (time.Since(g.Reviews.Updated) > MaxReviewAge)
+}
+
 func gameExpired(g *objects.JSONGame) bool {
 	return time.Since(g.Updated) > MaxGameAge
 }
+
+// CountOutdated returns the number of games cached with an older schema
+// These are refetched the next time they're requested.
+func (g *GameCache) CountOutdated() int {
+	n := 0
+	for _, gm := range *g {
+		if gm.Outdated() {
+			n++
+		}
+	}
+	return n
+}
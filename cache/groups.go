@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/vultour/steamcli/objects"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Add adds the specified group to the cache, replacing an older copy.
+func (g *GroupCache) Add(group *objects.XMLGroup) {
+	for i := range *g {
+		if (*g)[i].GroupID64 == group.GroupID64 {
+			(*g)[i] = group
+			return
+		}
+	}
+	*g = append(*g, group)
+}
+
+// Find searches the group cache for the specified ID or group name.
+// Groups holding fewer members than requested by max are not returned, max
+// of 0 means all members.
+func (g *GroupCache) Find(id string, max int) (*objects.XMLGroup, bool) {
+	log.WithField("id", id).Debug("Searching for group")
+	g.PurgeExpired()
+	for _, gr := range *g {
+		if (strconv.FormatInt(gr.GroupID64, 10) != id) &&
+			(strings.ToLower(gr.URL) != strings.ToLower(id)) {
+			continue
+		}
+		if len(gr.Members) >= gr.MemberCount {
+			return gr, true
+		}
+		if (max > 0) && (len(gr.Members) >= max) {
+			return gr, true
+		}
+		log.WithField("id", id).Debug("Cached group has too few members")
+	}
+	return nil, false
+}
+
+// PurgeExpired deletes all expired groups.
+func (g *GroupCache) PurgeExpired() {
+	log.Debug("Purging expired groups")
+	kept := make(GroupCache, 0, len(*g))
+	for _, gr := range *g {
+		if groupExpired(gr) {
+			log.WithField("name", gr.Name).Debug("Purging group")
+			continue
+		}
+		kept = append(kept, gr)
+	}
+	*g = kept
+}
+
+// groupExpired determines whether the specified group is too old.
+func groupExpired(g *objects.XMLGroup) bool {
+	return time.Since(g.Updated) > MaxGroupAge
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"sort"
+	"time"
+)
+
+// The following variables control how often Checkpoint writes the cache, it's
+// saved once either of them is reached
+var (
+	SaveEvery    = 50               // Changes since the last save
+	SaveInterval = 30 * time.Second // Time since the last save
+)
+
+// AddPending queues the games for fetching, the queue is saved with the cache
+// so an interrupted update can be resumed
+func (c *Cache) AddPending(appids []int) {
+	c.Lock()
+	defer c.Unlock()
+	queued := make(map[int]struct{}, len(c.Pending))
+	for _, id := range c.Pending {
+		queued[id] = struct{}{}
+	}
+	for _, id := range appids {
+		if _, ok := queued[id]; !ok {
+			c.Pending = append(c.Pending, id)
+			queued[id] = struct{}{}
+		}
+	}
+	c.changes++
+}
+
+// RemovePending removes the game from the fetch queue
+func (c *Cache) RemovePending(appid int) {
+	c.Lock()
+	defer c.Unlock()
+	for i, id := range c.Pending {
+		if id == appid {
+			c.Pending = append(c.Pending[:i], c.Pending[i+1:]...)
+			c.changes++
+			return
+		}
+	}
+}
+
+// PendingGames returns the games queued for fetching
+func (c *Cache) PendingGames() []int {
+	c.RLock()
+	defer c.RUnlock()
+	ret := append([]int(nil), c.Pending...)
+	sort.Ints(ret)
+	return ret
+}
+
+// Checkpoint saves the cache if SaveEvery changes were made or SaveInterval
+// passed since it was last saved, otherwise it does nothing
+// Call Save once done to write the remaining changes.
+func (c *Cache) Checkpoint() error {
+	c.RLock()
+	due := (c.changes >= SaveEvery) ||
+		((c.changes > 0) && (time.Since(c.lastSave) >= SaveInterval))
+	c.RUnlock()
+	if !due {
+		return nil
+	}
+	return c.Save()
+}
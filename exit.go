@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"gitlab.com/vultour/steamcli/steamerr"
+)
+
+// The following constants are the exit codes, see the README
+const (
+	exitError       = 1 // Arguments couldn't be parsed, or an unclassified error
+	exitInvalid     = 2 // Invalid arguments or IDs
+	exitNoResult    = 3 // Nothing to show
+	exitNoCommand   = 4
+	exitCache       = 5
+	exitNetwork     = 6
+	exitRateLimited = 7
+	exitNotFound    = 8
+	exitPrivate     = 9
+	exitDecode      = 10
+	exitInterrupted = 130
+)
+
+// exitCodes maps the steamerr classes to exit codes
+var exitCodes = map[error]int{
+	steamerr.ErrInvalid:     exitInvalid,
+	steamerr.ErrCache:       exitCache,
+	steamerr.ErrNetwork:     exitNetwork,
+	steamerr.ErrRateLimited: exitRateLimited,
+	steamerr.ErrNotFound:    exitNotFound,
+	steamerr.ErrPrivate:     exitPrivate,
+	steamerr.ErrDecode:      exitDecode,
+}
+
+// firstErr is the first error a command ran into, it determines the exit code
+// once the command has finished
+var firstErr error
+
+// recordError remembers err unless an error was recorded already
+func recordError(err error) {
+	if firstErr == nil {
+		firstErr = err
+	}
+}
+
+// exitCode returns the exit code for the error
+func exitCode(err error) int {
+	if errors.Is(err, context.Canceled) {
+		return exitInterrupted
+	}
+	if code, ok := exitCodes[steamerr.ClassOf(err)]; ok {
+		return code
+	}
+	return exitError
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"gitlab.com/vultour/steamcli/aggregator"
+	"gitlab.com/vultour/steamcli/api/profile"
+	"gitlab.com/vultour/steamcli/cache"
+	"gitlab.com/vultour/steamcli/id"
+	"gitlab.com/vultour/steamcli/steamerr"
+)
+
+func TestExitCode(t *testing.T) {
+	_, parseErr := id.New("not an id")
+	if parseErr == nil {
+		t.Fatal("id.New() accepted an invalid ID")
+	}
+	refused := &url.Error{Op: "Get", URL: "https://steamcommunity.com", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"unclassified", errors.New("something"), exitError},
+		{"cancelled", context.Canceled, exitInterrupted},
+		{"cancelled request", &profile.RequestError{Detail: "Could not perform request", Underlying: &url.Error{Op: "Get", Err: context.Canceled}, Class: steamerr.ErrNetwork}, exitInterrupted},
+		{"parse error", parseErr, exitInvalid},
+		{"wrapped parse error", fmt.Errorf("--id: %w", parseErr), exitInvalid},
+		{"private profile", &profile.ProfileError{ID: "76561197960287930", Err: profile.ErrProfilePrivate}, exitPrivate},
+		{"private games", &profile.ProfileError{ID: "76561197960287930", Err: profile.ErrGamesPrivate}, exitPrivate},
+		{"private friends", &profile.ProfileError{ID: "76561197960287930", Err: profile.ErrFriendsPrivate}, exitPrivate},
+		{"missing profile", &profile.ProfileError{ID: "76561197960287930", Err: profile.ErrProfileNotFound}, exitNotFound},
+		{"network request", &profile.RequestError{Detail: "Could not perform request", Underlying: refused, Class: steamerr.ErrNetwork}, exitNetwork},
+		{"rate limited request", &profile.RequestError{Detail: "Could not perform request", Underlying: steamerr.New(steamerr.ErrRateLimited, "kept rate limiting"), Class: steamerr.ErrNetwork}, exitRateLimited},
+		{"undecodable request", &profile.RequestError{Detail: "Could not decode XML", Class: steamerr.ErrDecode}, exitDecode},
+		{"unclassified request", &profile.RequestError{Detail: "Could not retrieve profile", Underlying: errors.New("EOF")}, exitError},
+		{"profile behind a request", &profile.RequestError{Detail: "Could not retrieve profile's games", Underlying: &profile.ProfileError{Err: profile.ErrGamesPrivate}}, exitPrivate},
+		{"corrupt cache", &cache.FileError{Path: "cache.json", Err: cache.ErrCorrupt, Cause: errors.New("unexpected end of JSON input")}, exitCache},
+		{"unreadable cache", fmt.Errorf("loading: %w", &cache.FileError{Err: cache.ErrUnreadable, Cause: refused}), exitCache},
+		{"missing game", fmt.Errorf("%w: %s", aggregator.ErrMissingGame, "10"), exitDecode},
+		{"no store page", aggregator.ErrNoStorePage, exitNotFound},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.code {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.code)
+		}
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	defer func(old error) { firstErr = old }(firstErr)
+	firstErr = nil
+
+	recordError(nil)
+	first := steamerr.New(steamerr.ErrPrivate, "profile is private")
+	recordError(first)
+	recordError(steamerr.New(steamerr.ErrNetwork, "request failed"))
+	if firstErr != first {
+		t.Errorf("firstErr = %v, want the first error", firstErr)
+	}
+}
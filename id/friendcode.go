@@ -0,0 +1,142 @@
+package id
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// csgoCodeDigits are the digits of the base32 encoding used by CS:GO and CS2
+// friend codes
+const csgoCodeDigits = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// csgoCodePrefix is the part of the encoded ID that's always the same for
+// individual accounts and is left out of the friend code
+const csgoCodePrefix = "AAAA-"
+
+// FromAccountID returns the ID of the individual account in the public
+// universe with the given account ID (the Z in [U:1:Z])
+func FromAccountID(account uint32) *SteamID {
+	return NewIndividual(account, InstanceDesktop)
+}
+
+// FriendCode returns the code shown as the friend code in the Steam client,
+// which is the account ID
+func (id *SteamID) FriendCode() string {
+	return strconv.FormatUint(uint64(id.Full.AccountID()), 10)
+}
+
+// InviteCode returns the code used in s.team/p/ friend links, e.g. "hj-qp"
+func (id *SteamID) InviteCode() string {
+	code := []byte(strconv.FormatUint(uint64(id.Full.AccountID()), 16))
+	for i, c := range code {
+		code[i] = inviteCodeDigits[strings.IndexByte("0123456789abcdef", c)]
+	}
+	if len(code) > 3 {
+		half := len(code) / 2
+		return string(code[:half]) + "-" + string(code[half:])
+	}
+	return string(code)
+}
+
+// InviteURL returns the s.team/p/ friend link of the ID (without protocol)
+func (id *SteamID) InviteURL() string {
+	return ShortLinkHost + "/" + ShortLinkPath + "/" + id.InviteCode()
+}
+
+// FromInviteCode parses the code used in s.team/p/ friend links
+func FromInviteCode(code string) (*SteamID, error) {
+	return parseInviteCode(code)
+}
+
+// CSGOFriendCode returns the friend code used by CS:GO and CS2, e.g.
+// "SUCVS-FADA"
+// Only individual accounts have a friend code, the string is empty otherwise.
+func (id *SteamID) CSGOFriendCode() string {
+	if id.Full.Type != AccountIndividual {
+		return ""
+	}
+	account := id.Full.AccountID()
+	h := csgoCodeHash(account)
+
+	// Every nibble of the account ID is preceded by a bit of the hash
+	var r uint64
+	for i := uint(0); i < 8; i++ {
+		nibble := uint64(account>>(i*4)) & 0xF
+		a := (r << 4) | nibble
+		r = ((r >> 28) << 32) | a
+		r = ((r >> 31) << 32) | ((a << 1) | uint64((h>>i)&1))
+	}
+	r = swapBytes(r)
+
+	code := make([]byte, 0, 15)
+	for i := 0; i < 13; i++ {
+		if (i == 4) || (i == 9) {
+			code = append(code, '-')
+		}
+		code = append(code, csgoCodeDigits[r&0x1F])
+		r >>= 5
+	}
+	return string(code[len(csgoCodePrefix):])
+}
+
+// FromCSGOFriendCode parses a CS:GO or CS2 friend code, e.g. "SUCVS-FADA"
+// The code contains a checksum, codes which don't match it are rejected.
+func FromCSGOFriendCode(code string) (*SteamID, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !strings.HasPrefix(code, csgoCodePrefix) {
+		code = csgoCodePrefix + code
+	}
+	if (len(code) != 15) || (code[4] != '-') || (code[10] != '-') {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse CS:GO friend code",
+			underlying: "code isn't in the AAAAA-AAAA format",
+		}
+	}
+
+	var r uint64
+	for i, c := range strings.Replace(code, "-", "", -1) {
+		d := strings.IndexRune(csgoCodeDigits, c)
+		if d < 0 {
+			return &SteamID{}, &ParseError{
+				detail:     "Could not parse CS:GO friend code",
+				underlying: "invalid character '" + string(c) + "'",
+			}
+		}
+		r |= uint64(d) << (5 * uint(i))
+	}
+	r = swapBytes(r)
+
+	var account uint32
+	for i := 0; i < 8; i++ {
+		r >>= 1
+		account = (account << 4) | uint32(r&0xF)
+		r >>= 4
+	}
+
+	id := FromAccountID(account)
+	if csgoCodePrefix+id.CSGOFriendCode() != code {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse CS:GO friend code",
+			underlying: "checksum mismatch",
+		}
+	}
+	return id, nil
+}
+
+// csgoCodeHash returns the hash whose bits are mixed into a CS:GO friend code
+func csgoCodeHash(account uint32) uint32 {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint32(b, account)
+	copy(b[4:], "OGSC") // "CSGO" followed by the big endian ID, reversed
+	sum := md5.Sum(b)
+	return binary.LittleEndian.Uint32(sum[:4])
+}
+
+// swapBytes reverses the byte order of a 64bit value
+func swapBytes(v uint64) uint64 {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return binary.LittleEndian.Uint64(b)
+}
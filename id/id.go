@@ -3,8 +3,11 @@ package id
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
+
+	"gitlab.com/vultour/steamcli/steamerr"
 )
 
 // The following constants describe the Universe an ID can be attached to
@@ -39,18 +42,55 @@ const (
 	CommunityBaseURL = "steamcommunity.com"
 )
 
+// The following constants describe the instance of individual accounts
+// Clans and anonymous accounts use InstanceAll.
+const (
+	InstanceAll     = 0
+	InstanceDesktop = 1
+	InstanceConsole = 2
+	InstanceWeb     = 4
+)
+
+// The following constants are flags in the instance of chat IDs
+const (
+	ChatInstanceFlagClan  = (instanceMask + 1) >> 1
+	ChatInstanceFlagLobby = (instanceMask + 1) >> 2
+	ChatInstanceFlagMMS   = (instanceMask + 1) >> 3
+)
+
 // CommunityPath describes the URL path for a given Account type
+// Groups use gid/ as groups/ only works with their custom URL names.
 var CommunityPath = map[int]string{
-	1: "profiles/id", // AccountIndividual
-	7: "groups/id",   // AccountClan
+	1: "profiles", // AccountIndividual
+	7: "gid",      // AccountClan
 }
 
+// ErrNoCommunityURL is returned for account types without a community page
+var ErrNoCommunityURL = steamerr.New(steamerr.ErrInvalid, "account type has no community URL")
+
 // CommunityIdentifier is a special value used for generating a community URL
 var CommunityIdentifier = map[int]int64{
 	1: 0x0110000100000000, // AccountIndividual
 	7: 0x0170000000000000, // AccountClan
 }
 
+// The following constants describe the layout of the bit fields in a 64bit
+// Steam ID, from the least significant bit:
+// account number parity (1), account number (31), instance (20), type (4),
+// universe (8)
+const (
+	accountNumberShift = 1
+	instanceShift      = 32
+	typeShift          = 52
+	universeShift      = 56
+
+	parityMask        = 0x1
+	accountNumberMask = 0x7FFFFFFF
+	instanceMask      = 0xFFFFF
+	typeMask          = 0xF
+	universeMask      = 0xFF
+)
+
 // SteamIDFull is a completely decoded Steam ID
 type SteamIDFull struct {
 	Universe      uint64
@@ -79,8 +119,75 @@ type ParseError struct {
 	underlying string
 }
 
+// Decode splits a 64bit Steam ID into its bit fields
+func Decode(id64 uint64) SteamIDFull {
+	return SteamIDFull{
+		Y:             id64 & parityMask,
+		AccountNumber: (id64 >> accountNumberShift) & accountNumberMask,
+		Instance:      (id64 >> instanceShift) & instanceMask,
+		Type:          (id64 >> typeShift) & typeMask,
+		Universe:      (id64 >> universeShift) & universeMask,
+	}
+}
+
+// FromFull returns a SteamID with all representations computed from the
+// decoded ID
+func FromFull(full SteamIDFull) *SteamID {
+	return &SteamID{
+		Full: full,
+		Triplet: SteamIDTriplet{
+			X: int(full.Universe),
+			Y: int(full.Y),
+			Z: int(full.AccountNumber),
+		},
+	}
+}
+
+// NewIndividual returns the ID of a user account in the public universe
+// The instance is one of the Instance* constants, usually InstanceDesktop.
+func NewIndividual(account uint32, instance uint64) *SteamID {
+	return newPublic(AccountIndividual, account, instance)
+}
+
+// NewClan returns the ID of a group in the public universe
+func NewClan(account uint32) *SteamID {
+	return newPublic(AccountClan, account, InstanceAll)
+}
+
+// NewGameServer returns the ID of a persistent game server in the public
+// universe
+func NewGameServer(account uint32, instance uint64) *SteamID {
+	return newPublic(AccountGameServer, account, instance)
+}
+
+// NewAnonGameServer returns the ID of an anonymous game server in the public
+// universe, the instance distinguishes servers sharing the account
+func NewAnonGameServer(account uint32, instance uint64) *SteamID {
+	return newPublic(AccountAnonGameServer, account, instance)
+}
+
+// NewAnonUser returns the ID of an anonymous user account in the public
+// universe
+func NewAnonUser(account uint32, instance uint64) *SteamID {
+	return newPublic(AccountAnonUser, account, instance)
+}
+
+// newPublic returns an ID of the given type in the public universe
+func newPublic(accountType uint64, account uint32, instance uint64) *SteamID {
+	return FromFull(SteamIDFull{
+		Universe:      UniversePublic,
+		Type:          accountType,
+		Instance:      instance & instanceMask,
+		AccountNumber: uint64(account) >> accountNumberShift,
+		Y:             uint64(account) & parityMask,
+	})
+}
+
 // New attempts to parse the given string into a Steam ID
-// This will be a networked operation if provided with a community ID
+// Accepted are 64bit IDs, STEAM_X:Y:Z triplets, Steam3 IDs ([U:1:Z]), profile
+// links, s.team/p/ friend links and their codes, friend codes (account IDs),
+// and CS:GO friend codes. Community IDs (custom URL names) can't be resolved
+// offline, see Vanity.
 func New(id string) (*SteamID, error) {
 	idd := strings.TrimSpace(id)
 	if idd == "STEAM_ID_PENDING" {
@@ -92,29 +199,22 @@ func New(id string) (*SteamID, error) {
 	}
 
 	if id64, err := strconv.ParseUint(idd, 10, 64); err == nil {
-		idFull := SteamIDFull{
-			Y:             id64 & 0x1,
-			AccountNumber: id64 & 0xFFFFFFE,
-			Instance:      id64 & 0xFFFFF00000000,
-			Type:          id64 & 0xF0000000000000,
-			Universe:      id64 & 0xFF00000000000000,
+		// Friend codes in the Steam client are plain account IDs
+		if id64 <= math.MaxUint32 {
+			return FromAccountID(uint32(id64)), nil
 		}
-
-		if !(idFull.Type > AccountMaximumValue) &&
-			!(idFull.Universe > UniverseMaximumValue) {
-			return &SteamID{
-				Full: idFull,
-				Triplet: SteamIDTriplet{
-					X: int(idFull.Universe),
-					Y: int(idFull.Y),
-					Z: int(idFull.AccountNumber),
-				},
-			}, nil
+		idFull := Decode(id64)
+		if idFull.Valid() {
+			return FromFull(idFull), nil
+		}
+		return &SteamID{}, &ParseError{
+			detail:     "Invalid 64bit ID",
+			underlying: "universe or account type out of range",
 		}
 	}
 
 	if strings.HasPrefix(idd, "STEAM_") {
-		data := strings.Split(id[6:], ":")
+		data := strings.Split(idd[6:], ":")
 		if len(data) != 3 {
 			return &SteamID{}, &ParseError{
 				detail:     "Could not parse triplet",
@@ -122,21 +222,21 @@ func New(id string) (*SteamID, error) {
 			}
 		}
 
-		x, err := strconv.ParseInt(data[0], 10, 32)
+		x, err := strconv.ParseUint(data[0], 10, 8)
 		if err != nil {
 			return &SteamID{}, &ParseError{
 				detail:     "Could not parse triplet value X",
 				underlying: err.Error(),
 			}
 		}
-		y, err := strconv.ParseInt(data[1], 10, 32)
+		y, err := strconv.ParseUint(data[1], 10, 1)
 		if err != nil {
 			return &SteamID{}, &ParseError{
 				detail:     "Could not parse triplet value Y",
 				underlying: err.Error(),
 			}
 		}
-		z, err := strconv.ParseInt(data[2], 10, 32)
+		z, err := strconv.ParseUint(data[2], 10, 31)
 		if err != nil {
 			return &SteamID{}, &ParseError{
 				detail:     "Could not parse triplet value Z",
@@ -144,23 +244,43 @@ func New(id string) (*SteamID, error) {
 			}
 		}
 
-		triplet := SteamIDTriplet{
-			X: int(x),
-			Y: int(y),
-			Z: int(z),
+		// Older games use X = 0 for the public universe
+		if x == UniverseUnspecified {
+			x = UniversePublic
 		}
+		if x > UniverseMaximumValue {
+			return &SteamID{}, &ParseError{
+				detail:     "Could not parse triplet value X",
+				underlying: "universe out of range",
+			}
+		}
+
+		// Guessing type and instance! Use the constructors for other types.
+		return FromFull(SteamIDFull{
+			AccountNumber: z,
+			Y:             y,
+			Universe:      x,
+			Instance:      InstanceDesktop,
+			Type:          AccountIndividual,
+		}), nil
+	}
+
+	if strings.HasPrefix(idd, "[") || ((len(idd) > 2) && (idd[1] == ':')) {
+		return parseSteam3(idd)
+	}
+
+	if isURL(idd) {
+		return parseURL(idd)
+	}
+
+	if sid, err := FromCSGOFriendCode(idd); err == nil {
+		return sid, nil
+	}
 
-		// Guessing type and instance!
-		return &SteamID{
-			Triplet: triplet,
-			Full: SteamIDFull{
-				AccountNumber: uint64(triplet.Z),
-				Y:             uint64(triplet.Y),
-				Universe:      uint64(triplet.X),
-				Instance:      1,
-				Type:          1,
-			},
-		}, nil
+	// Bare invite codes are only accepted in the form the client shows them
+	if sid, err := parseInviteCode(idd); (err == nil) && strings.Contains(idd, "-") &&
+		(sid.InviteCode() == idd) {
+		return sid, nil
 	}
 
 	return &SteamID{}, &ParseError{detail: "Couldn't determine ID type"}
@@ -172,27 +292,155 @@ func (id *SteamID) CommunityID() int64 {
 	return int64(id.Triplet.Z*2) + int64(V) + int64(id.Triplet.Y)
 }
 
-// CommunityURL returns a link to the ID's profile (without protocol)
-func (id *SteamID) CommunityURL() string {
+// CommunityURL returns a link to the ID's profile or group page (without
+// protocol), ErrNoCommunityURL is returned for other account types
+func (id *SteamID) CommunityURL() (string, error) {
+	path, ok := CommunityPath[int(id.Full.Type)]
+	if !ok {
+		return "", ErrNoCommunityURL
+	}
 	return fmt.Sprintf(
 		"%s/%s/%d",
 		CommunityBaseURL,
-		CommunityPath[int(id.Full.Type)],
+		path,
 		id.CommunityID(),
-	)
+	), nil
 }
 
 // Get returns the 64bit SteamID (this is _not_ the Community ID!)
 func (id *SteamIDFull) Get() uint64 {
-	var ret uint64
-	ret = (ret | (id.Y << 63)) >> 31
-	ret = (ret | (id.AccountNumber << 33)) >> 20
-	ret = (ret | (id.Instance << 44)) >> 4
-	ret = (ret | (id.Type << 60)) >> 8
-	ret = (ret | (id.Universe << 56))
+	return (id.Universe&universeMask)<<universeShift |
+		(id.Type&typeMask)<<typeShift |
+		(id.Instance&instanceMask)<<instanceShift |
+		(id.AccountNumber&accountNumberMask)<<accountNumberShift |
+		(id.Y & parityMask)
+}
+
+// AccountID returns the 32bit account ID (the account number including the
+// parity bit), this is the ID used in Steam3 IDs
+func (id *SteamIDFull) AccountID() uint32 {
+	return uint32(id.AccountNumber<<accountNumberShift | id.Y)
+}
+
+// Valid determines whether the universe and account type are known values
+func (id *SteamIDFull) Valid() bool {
+	return (id.Universe > UniverseUnspecified) &&
+		(id.Universe <= UniverseMaximumValue) &&
+		(id.Type > AccountInvalid) &&
+		(id.Type <= AccountMaximumValue)
+}
+
+// Warnings returns problems with an otherwise parseable ID, e.g. values which
+// are valid but unusual for IDs found in the wild
+func (id *SteamID) Warnings() []string {
+	var ret []string
+	if !id.Full.Valid() {
+		ret = append(ret, "universe or account type out of range")
+	}
+	if id.Full.Universe != UniversePublic {
+		ret = append(ret, "not in the public universe")
+	}
+	if id.Full.AccountID() == 0 {
+		ret = append(ret, "account ID is zero")
+	}
+	if _, err := id.CommunityURL(); err != nil {
+		ret = append(ret, err.Error())
+	}
+	switch id.Full.Type {
+	case AccountIndividual:
+		if id.Full.Instance&^(InstanceDesktop|InstanceConsole|InstanceWeb) != 0 {
+			ret = append(ret, "individual account with unknown instance flags")
+		}
+	case AccountClan:
+		if id.Full.Instance != InstanceAll {
+			ret = append(ret, "clan with a non-zero instance")
+		}
+	}
 	return ret
 }
 
+// UniverseNames maps universes to their names
+var UniverseNames = map[uint64]string{
+	UniverseUnspecified: "Unspecified",
+	UniversePublic:      "Public",
+	UniverseBeta:        "Beta",
+	UniverseInternal:    "Internal",
+	UniverseDev:         "Dev",
+	UniverseRC:          "RC",
+}
+
+// AccountTypeNames maps account types to their names
+var AccountTypeNames = map[uint64]string{
+	AccountInvalid:        "Invalid",
+	AccountIndividual:     "Individual",
+	AccountMultiseat:      "Multiseat",
+	AccountGameServer:     "GameServer",
+	AccountAnonGameServer: "AnonGameServer",
+	AccountPending:        "Pending",
+	AccountContentServer:  "ContentServer",
+	AccountClan:           "Clan",
+	AccountChat:           "Chat",
+	AccountP2PSuperSeeder: "P2PSuperSeeder",
+	AccountAnonUser:       "AnonUser",
+}
+
+// Steam3TypeChar maps account types to the letter used in Steam3 IDs
+var Steam3TypeChar = map[uint64]string{
+	AccountInvalid:        "I",
+	AccountIndividual:     "U",
+	AccountMultiseat:      "M",
+	AccountGameServer:     "G",
+	AccountAnonGameServer: "A",
+	AccountPending:        "P",
+	AccountContentServer:  "C",
+	AccountClan:           "g",
+	AccountChat:           "T",
+	AccountP2PSuperSeeder: "I",
+	AccountAnonUser:       "a",
+}
+
+// Steam2 returns the ID in the STEAM_X:Y:Z format
+func (id *SteamID) Steam2() string {
+	return fmt.Sprintf("STEAM_%d:%d:%d", id.Triplet.X, id.Triplet.Y, id.Triplet.Z)
+}
+
+// Steam3 returns the ID in the Steam3 format, e.g. [U:1:22202]
+// The instance is only included where it's significant.
+func (id *SteamID) Steam3() string {
+	char, ok := Steam3TypeChar[id.Full.Type]
+	if !ok {
+		char = "I"
+	}
+
+	withInstance := false
+	switch id.Full.Type {
+	case AccountChat: // Chat IDs have separate letters for their flags
+		if id.Full.Instance&ChatInstanceFlagClan != 0 {
+			char = "c"
+		} else if id.Full.Instance&ChatInstanceFlagLobby != 0 {
+			char = "L"
+		}
+	case AccountAnonGameServer, AccountMultiseat:
+		withInstance = true
+	case AccountIndividual:
+		withInstance = id.Full.Instance != 1
+	}
+
+	if withInstance {
+		return fmt.Sprintf(
+			"[%s:%d:%d:%d]",
+			char, id.Full.Universe, id.Full.AccountID(), id.Full.Instance,
+		)
+	}
+	return fmt.Sprintf("[%s:%d:%d]", char, id.Full.Universe, id.Full.AccountID())
+}
+
+// Is reports whether target is steamerr.ErrInvalid, every ParseError is
+// caused by invalid input
+func (e *ParseError) Is(target error) bool {
+	return target == steamerr.ErrInvalid
+}
+
 func (e *ParseError) Error() string {
 	if e.underlying == "" {
 		e.underlying = "none"
@@ -205,8 +453,16 @@ func (e *ParseError) Error() string {
 }
 
 func (id *SteamID) String() string {
+	url, err := id.CommunityURL()
+	if err != nil {
+		url = err.Error()
+	}
 	return fmt.Sprintf(
 		`Steam ID:
+	Steam3: %s
+	Friend Code: %s
+	Invite: %s
+	CS:GO Friend Code: %s
 	Triplet: STEAM_%d:%d:%d
 		X: %d
 		Y: %d
@@ -220,12 +476,16 @@ func (id *SteamID) String() string {
 	Community:
 		ID: %d
 		URL: %s`,
+		id.Steam3(),
+		id.FriendCode(),
+		id.InviteURL(),
+		id.CSGOFriendCode(),
 		id.Triplet.X, id.Triplet.Y, id.Triplet.Z,
 		id.Triplet.X, id.Triplet.Y, id.Triplet.Z,
 		id.Full.Get(),
 		id.Full.AccountNumber, id.Full.Universe, id.Full.Y, id.Full.Instance,
 		id.Full.Type,
 		id.CommunityID(),
-		id.CommunityURL(),
+		url,
 	)
 }
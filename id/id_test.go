@@ -0,0 +1,199 @@
+package id
+
+import (
+	"errors"
+	"testing"
+
+	"gitlab.com/vultour/steamcli/steamerr"
+)
+
+// Known IDs of real accounts and groups
+const (
+	gabe64 = 76561197960287930 // Account ID 22202
+	clan64 = 103582791429521412
+)
+
+func TestDecodeGetRoundTrip(t *testing.T) {
+	tests := []SteamIDFull{
+		{Universe: UniversePublic, Type: AccountIndividual, Instance: InstanceDesktop, AccountNumber: 11101, Y: 0},
+		{Universe: UniversePublic, Type: AccountClan, Instance: InstanceAll, AccountNumber: 2, Y: 0},
+		{Universe: UniverseBeta, Type: AccountGameServer, Instance: InstanceWeb, AccountNumber: 1, Y: 1},
+		{Universe: UniverseDev, Type: AccountChat, Instance: ChatInstanceFlagClan | ChatInstanceFlagLobby, AccountNumber: 12345, Y: 1},
+		{Universe: universeMask, Type: typeMask, Instance: instanceMask, AccountNumber: accountNumberMask, Y: parityMask},
+		{},
+	}
+	for _, full := range tests {
+		id64 := full.Get()
+		if got := Decode(id64); got != full {
+			t.Errorf("Decode(%d) = %+v, want %+v", id64, got, full)
+		}
+		if got := Decode(id64); got.Get() != id64 {
+			t.Errorf("Decode(%d).Get() = %d", id64, got.Get())
+		}
+	}
+}
+
+func TestKnownIDs(t *testing.T) {
+	tests := []struct {
+		input     string
+		id64      uint64
+		steam2    string
+		steam3    string
+		community string
+	}{
+		{"76561197960287930", gabe64, "STEAM_1:0:11101", "[U:1:22202]", "steamcommunity.com/profiles/76561197960287930"},
+		{"STEAM_1:0:11101", gabe64, "STEAM_1:0:11101", "[U:1:22202]", "steamcommunity.com/profiles/76561197960287930"},
+		{"[U:1:22202]", gabe64, "STEAM_1:0:11101", "[U:1:22202]", "steamcommunity.com/profiles/76561197960287930"},
+		{"103582791429521412", clan64, "STEAM_1:0:2", "[g:1:4]", "steamcommunity.com/gid/103582791429521412"},
+		{"[g:1:4]", clan64, "STEAM_1:0:2", "[g:1:4]", "steamcommunity.com/gid/103582791429521412"},
+	}
+	for _, tt := range tests {
+		x, err := New(tt.input)
+		if err != nil {
+			t.Errorf("New(%q) = %v", tt.input, err)
+			continue
+		}
+		if got := x.Full.Get(); got != tt.id64 {
+			t.Errorf("New(%q).Full.Get() = %d, want %d", tt.input, got, tt.id64)
+		}
+		if got := x.Steam2(); got != tt.steam2 {
+			t.Errorf("New(%q).Steam2() = %s, want %s", tt.input, got, tt.steam2)
+		}
+		if got := x.Steam3(); got != tt.steam3 {
+			t.Errorf("New(%q).Steam3() = %s, want %s", tt.input, got, tt.steam3)
+		}
+		if got, err := x.CommunityURL(); (err != nil) || (got != tt.community) {
+			t.Errorf("New(%q).CommunityURL() = %s, %v, want %s", tt.input, got, err, tt.community)
+		}
+
+		// Every representation parses back into the same ID
+		for _, s := range []string{tt.steam3, tt.community} {
+			y, err := New(s)
+			if err != nil {
+				t.Errorf("New(%q) = %v", s, err)
+			} else if y.Full != x.Full {
+				t.Errorf("New(%q) = %+v, want %+v", s, y.Full, x.Full)
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		id64  uint64
+	}{
+		{"STEAM_0:0:11101", gabe64}, // Older games use universe 0
+		{"  STEAM_1:0:11101\n", gabe64},
+		{"U:1:22202", gabe64},
+		{"[U:1:22202:1]", gabe64},
+		{"https://steamcommunity.com/profiles/76561197960287930/", gabe64},
+		{"http://www.steamcommunity.com/profiles/76561197960287930/games?tab=all", gabe64},
+		{"steamcommunity.com/gid/103582791429521412", clan64},
+		{"https://s.team/p/hj-qp", gabe64},
+		{"s.team/p/hj-qp/abcdefgh", gabe64},
+		{"22202", gabe64}, // Friend code
+		{"hj-qp", gabe64},
+		{"SUCVS-FADA", gabe64},
+	}
+	for _, tt := range tests {
+		x, err := New(tt.input)
+		if err != nil {
+			t.Errorf("New(%q) = %v", tt.input, err)
+			continue
+		}
+		if got := x.Full.Get(); got != tt.id64 {
+			t.Errorf("New(%q) = %d, want %d", tt.input, got, tt.id64)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"STEAM_ID_PENDING",
+		"UNKNOWN",
+		"STEAM_1:2:11101",
+		"STEAM_9:0:11101",
+		"STEAM_1:0",
+		"[X:1:22202]",
+		"76561197960287930x",
+		"https://steamcommunity.com/id/gabelogannewell",
+		"https://example.com/profiles/76561197960287930",
+		"hjqp", // Bare invite codes need the dash
+		"gabelogannewell",
+	}
+	for _, s := range tests {
+		x, err := New(s)
+		if err == nil {
+			t.Errorf("New(%q) = %d, want an error", s, x.Full.Get())
+			continue
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) || !errors.Is(err, steamerr.ErrInvalid) {
+			t.Errorf("New(%q) error = %#v, want an invalid *ParseError", s, err)
+		}
+	}
+}
+
+func TestVanity(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		ok    bool
+	}{
+		{"https://steamcommunity.com/id/gabelogannewell/", "gabelogannewell", true},
+		{"steamcommunity.com/id/gabelogannewell/games", "gabelogannewell", true},
+		{"https://steamcommunity.com/profiles/76561197960287930", "", false},
+		{"gabelogannewell", "", false},
+	}
+	for _, tt := range tests {
+		if name, ok := Vanity(tt.input); (name != tt.name) || (ok != tt.ok) {
+			t.Errorf("Vanity(%q) = %q, %t, want %q, %t", tt.input, name, ok, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestFriendCodes(t *testing.T) {
+	tests := []struct {
+		account uint32
+		friend  string
+		invite  string
+		csgo    string
+	}{
+		{22202, "22202", "hj-qp", "SUCVS-FADA"},
+		{123456, "123456", "cv-dgb", ""},
+	}
+	for _, tt := range tests {
+		x := FromAccountID(tt.account)
+		if got := x.FriendCode(); got != tt.friend {
+			t.Errorf("%d: FriendCode() = %s, want %s", tt.account, got, tt.friend)
+		}
+		if got := x.InviteCode(); got != tt.invite {
+			t.Errorf("%d: InviteCode() = %s, want %s", tt.account, got, tt.invite)
+		}
+		if got, want := x.InviteURL(), "s.team/p/"+tt.invite; got != want {
+			t.Errorf("%d: InviteURL() = %s, want %s", tt.account, got, want)
+		}
+		if tt.csgo != "" {
+			if got := x.CSGOFriendCode(); got != tt.csgo {
+				t.Errorf("%d: CSGOFriendCode() = %s, want %s", tt.account, got, tt.csgo)
+			}
+		}
+
+		// The codes parse back into the account
+		y, err := FromInviteCode(tt.invite)
+		if (err != nil) || (y.Full != x.Full) {
+			t.Errorf("FromInviteCode(%q) = %+v, %v, want %+v", tt.invite, y.Full, err, x.Full)
+		}
+		z, err := FromCSGOFriendCode(x.CSGOFriendCode())
+		if (err != nil) || (z.Full != x.Full) {
+			t.Errorf("FromCSGOFriendCode(%q) = %+v, %v, want %+v", x.CSGOFriendCode(), z.Full, err, x.Full)
+		}
+	}
+
+	// Clans don't have CS:GO friend codes
+	if code := NewClan(2).CSGOFriendCode(); code != "" {
+		t.Errorf("clan CSGOFriendCode() = %q, want none", code)
+	}
+}
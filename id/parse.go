@@ -0,0 +1,188 @@
+package id
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The following constants describe the hosts of links containing Steam IDs
+const (
+	ShortLinkHost = "s.team"
+	ShortLinkPath = "p"
+)
+
+// inviteCodeDigits replace the hexadecimal digits of an account ID in the
+// codes of s.team/p/ links
+const inviteCodeDigits = "bcdfghjkmnpqrtvw"
+
+// parseSteam3 parses an ID in the Steam3 format, e.g. [U:1:22202]
+// The brackets are optional.
+func parseSteam3(s string) (*SteamID, error) {
+	data := strings.Split(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"), ":")
+	if (len(data) < 3) || (len(data) > 4) {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse Steam3 ID",
+			underlying: "ID doesn't contain three or four parts",
+		}
+	}
+
+	var accountType uint64
+	var instance uint64
+	found := false
+	for t, char := range Steam3TypeChar {
+		if (char == data[0]) && (t != AccountP2PSuperSeeder) {
+			accountType, found = t, true
+			break
+		}
+	}
+	switch data[0] { // Chat IDs have separate letters for their flags
+	case "c":
+		accountType, instance, found = AccountChat, ChatInstanceFlagClan, true
+	case "L":
+		accountType, instance, found = AccountChat, ChatInstanceFlagLobby, true
+	}
+	if !found {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse Steam3 ID",
+			underlying: "unknown account type '" + data[0] + "'",
+		}
+	}
+
+	universe, err := strconv.ParseUint(data[1], 10, 8)
+	if (err != nil) || (universe > UniverseMaximumValue) {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse Steam3 universe",
+			underlying: data[1],
+		}
+	}
+	account, err := strconv.ParseUint(data[2], 10, 32)
+	if err != nil {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse Steam3 account ID",
+			underlying: err.Error(),
+		}
+	}
+
+	if len(data) == 4 {
+		instance, err = strconv.ParseUint(data[3], 10, 20)
+		if err != nil {
+			return &SteamID{}, &ParseError{
+				detail:     "Could not parse Steam3 instance",
+				underlying: err.Error(),
+			}
+		}
+	} else if (accountType == AccountIndividual) || (accountType == AccountGameServer) {
+		instance = InstanceDesktop
+	}
+
+	return FromFull(SteamIDFull{
+		Universe:      universe,
+		Type:          accountType,
+		Instance:      instance,
+		AccountNumber: account >> accountNumberShift,
+		Y:             account & parityMask,
+	}), nil
+}
+
+// parseURL parses profile and group links (steamcommunity.com/profiles/...,
+// steamcommunity.com/gid/...) and short friend links (s.team/p/...)
+// Links to custom URLs can't be parsed offline, see Vanity.
+func parseURL(s string) (*SteamID, error) {
+	parts := urlParts(s)
+	if len(parts) < 3 {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse URL",
+			underlying: "URL doesn't contain an ID",
+		}
+	}
+
+	switch {
+	case (parts[0] == CommunityBaseURL) &&
+		((parts[1] == CommunityPath[AccountIndividual]) || (parts[1] == CommunityPath[AccountClan])):
+		id64, err := strconv.ParseUint(parts[2], 10, 64)
+		if err != nil {
+			return &SteamID{}, &ParseError{
+				detail:     "Could not parse community URL",
+				underlying: err.Error(),
+			}
+		}
+		full := Decode(id64)
+		if !full.Valid() {
+			return &SteamID{}, &ParseError{
+				detail:     "Invalid 64bit ID in community URL",
+				underlying: "universe or account type out of range",
+			}
+		}
+		return FromFull(full), nil
+	case (parts[0] == ShortLinkHost) && (parts[1] == ShortLinkPath):
+		return parseInviteCode(parts[2])
+	}
+
+	return &SteamID{}, &ParseError{
+		detail:     "Could not parse URL",
+		underlying: "unsupported link",
+	}
+}
+
+// parseInviteCode parses the code used in s.team/p/ links, e.g. "hj-qp"
+func parseInviteCode(code string) (*SteamID, error) {
+	hex := make([]byte, 0, 8)
+	for _, c := range strings.ToLower(strings.Replace(code, "-", "", -1)) {
+		i := strings.IndexRune(inviteCodeDigits, c)
+		if i < 0 {
+			return &SteamID{}, &ParseError{
+				detail:     "Could not parse invite code",
+				underlying: "invalid character '" + string(c) + "'",
+			}
+		}
+		hex = append(hex, "0123456789abcdef"[i])
+	}
+
+	account, err := strconv.ParseUint(string(hex), 16, 32)
+	if err != nil {
+		return &SteamID{}, &ParseError{
+			detail:     "Could not parse invite code",
+			underlying: err.Error(),
+		}
+	}
+	return FromAccountID(uint32(account)), nil
+}
+
+// Vanity returns the custom URL name from a link to a profile with a custom
+// URL (steamcommunity.com/id/...)
+// The bool returns false if the string isn't such a link.
+func Vanity(s string) (string, bool) {
+	parts := urlParts(s)
+	if (len(parts) < 3) || (parts[0] != CommunityBaseURL) || (parts[1] != "id") {
+		return "", false
+	}
+	return parts[2], true
+}
+
+// isURL determines whether the string looks like one of the supported links
+func isURL(s string) bool {
+	parts := urlParts(s)
+	return (len(parts) > 0) &&
+		((parts[0] == CommunityBaseURL) || (parts[0] == ShortLinkHost))
+}
+
+// urlParts splits a link into the host and path segments, dropping the
+// protocol, "www.", query, and empty segments
+func urlParts(s string) []string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimPrefix(strings.ToLower(s), "www.")
+
+	ret := make([]string, 0, 4)
+	for _, p := range strings.Split(s, "/") {
+		if p != "" {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
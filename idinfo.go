@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab.com/vultour/steamcli/api/profile"
+	"gitlab.com/vultour/steamcli/id"
+)
+
+// steamIDInfo contains every representation of a Steam ID given to the id
+// subcommand
+type steamIDInfo struct {
+	Input    string   `json:"input"`
+	Resolved bool     `json:"resolved,omitempty"` // Looked up over the network
+	Error    string   `json:"error,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	err      error    // Why Error is set
+
+	Valid          bool   `json:"valid"`
+	ID64           string `json:"id64,omitempty"` // A string, doesn't fit a JSON number
+	Steam2         string `json:"steam2,omitempty"`
+	Steam3         string `json:"steam3,omitempty"`
+	AccountID      uint32 `json:"account_id"`
+	AccountNumber  uint64 `json:"account_number"`
+	Universe       uint64 `json:"universe"`
+	UniverseName   string `json:"universe_name,omitempty"`
+	Type           uint64 `json:"type"`
+	TypeName       string `json:"type_name,omitempty"`
+	Instance       uint64 `json:"instance"`
+	CommunityURL   string `json:"community_url,omitempty"`
+	FriendCode     string `json:"friend_code,omitempty"`
+	InviteURL      string `json:"invite_url,omitempty"`
+	CSGOFriendCode string `json:"csgo_friend_code,omitempty"`
+}
+
+// newSteamIDInfo parses the value, custom URL names are only looked up if
+// resolve is set
+func newSteamIDInfo(ctx context.Context, v string, resolve bool) *steamIDInfo {
+	info := &steamIDInfo{Input: v}
+
+	x, err := id.New(v)
+	if err != nil {
+		name, ok := id.Vanity(v)
+		if !ok {
+			name = v
+		}
+		if !resolve {
+			info.Error = fmt.Sprintf("%s, custom URL names need --resolve", err)
+			info.err = err
+			return info
+		}
+		id64, rerr := profile.ResolveContext(ctx, name)
+		if rerr != nil {
+			info.Error = fmt.Sprintf("could not resolve '%s': %s", name, rerr)
+			info.err = rerr
+			return info
+		}
+		x = id.FromFull(id.Decode(id64))
+		info.Resolved = true
+	}
+
+	info.Valid = x.Full.Valid()
+	info.Warnings = x.Warnings()
+	info.ID64 = strconv.FormatUint(x.Full.Get(), 10)
+	info.Steam2 = x.Steam2()
+	info.Steam3 = x.Steam3()
+	info.AccountID = x.Full.AccountID()
+	info.AccountNumber = x.Full.AccountNumber
+	info.Universe = x.Full.Universe
+	info.UniverseName = id.UniverseNames[x.Full.Universe]
+	info.Type = x.Full.Type
+	info.TypeName = id.AccountTypeNames[x.Full.Type]
+	info.Instance = x.Full.Instance
+	if u, err := x.CommunityURL(); err == nil {
+		info.CommunityURL = u
+	}
+	if x.Full.Type == id.AccountIndividual {
+		info.FriendCode = x.FriendCode()
+		info.InviteURL = x.InviteURL()
+		info.CSGOFriendCode = x.CSGOFriendCode()
+	}
+	return info
+}
+
+func (i *steamIDInfo) String() string {
+	var b strings.Builder
+	line := func(k string, v interface{}) {
+		fmt.Fprintf(&b, "%-16s: %v\n", k, v)
+	}
+
+	line("Input", i.Input)
+	if i.Error != "" {
+		line("Error", i.Error)
+		return b.String()
+	}
+	if i.Resolved {
+		line("Resolved", "yes")
+	}
+	line("64bit", i.ID64)
+	line("Steam2", i.Steam2)
+	line("Steam3", i.Steam3)
+	line("Account ID", i.AccountID)
+	line("Account number", i.AccountNumber)
+	line("Universe", fmt.Sprintf("%s (%d)", i.UniverseName, i.Universe))
+	line("Type", fmt.Sprintf("%s (%d)", i.TypeName, i.Type))
+	line("Instance", i.Instance)
+	if i.CommunityURL != "" {
+		line("Community URL", i.CommunityURL)
+	}
+	if i.FriendCode != "" {
+		line("Friend code", i.FriendCode)
+		line("Invite link", i.InviteURL)
+		line("CS:GO code", i.CSGOFriendCode)
+	}
+	if i.Valid {
+		line("Valid", "yes")
+	} else {
+		line("Valid", "no")
+	}
+	for _, w := range i.Warnings {
+		line("Warning", w)
+	}
+	return b.String()
+}
@@ -1,60 +1,259 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 
 	"gitlab.com/vultour/steamcli/aggregator"
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/api/profile"
 	"gitlab.com/vultour/steamcli/cache"
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/ratelimit"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// The following variables are set up in main, they point at the progress bar
+// if one is shown
+var (
+	stderr     io.Writer = os.Stderr // Status messages are written here
+	progress   *progressBar
+	onProgress aggregator.ProgressFunc // Used by all aggregators
+)
+
 func main() {
 	a := ParseArgs()
 
 	cache.FileLocation = *a.CacheFile
 	aggregator.ParallelUpdates = *a.CacheParallel
+	aggregator.Workers = *a.Workers
+	aggregator.Country = strings.ToLower(*a.Country)
+	aggregator.Language = *a.Language
+	profile.APIKey = *a.APIKey
+	endpoints.Config.FromEnv()
+	endpoints.Config.Set(*a.CommunityURL, *a.StoreURL, *a.WebAPIURL)
+	for host, limit := range a.RateLimits {
+		ratelimit.Default.SetLimit(host, limit)
+	}
+	ratelimit.Default.MaxRetries = *a.MaxRetries
+	if !*a.NoProgress {
+		if progress = newProgressBar(os.Stderr); progress != nil {
+			stderr = progress
+			onProgress = progress.Update
+			log.SetOutput(progress)
+		}
+	}
+	ctx := interruptContext()
 
 	if a.Games.Command.Happened() {
-		gameCommand(a)
+		gameCommand(ctx, a)
+	} else if a.Recent.Command.Happened() {
+		recentCommand(ctx, a)
+	} else if a.Achievements.Command.Happened() {
+		achievementsCommand(ctx, a)
+	} else if a.ID.Command.Happened() {
+		idCommand(ctx, a)
 	} else if a.Cache.Command.Happened() {
-		cacheCommand(a)
+		cacheCommand(ctx, a)
 	} else {
 		fmt.Print(a.Parser.Usage("No subcommand was specified"))
-		os.Exit(4)
+		os.Exit(exitNoCommand)
+	}
+
+	if firstErr != nil {
+		if progress != nil {
+			progress.Finish()
+		}
+		os.Exit(exitCode(firstErr))
 	}
 }
 
-func gameCommand(a *Arguments) {
-	log.WithField("subcmd", ".games").Debug("Subcommand entered")
-	agg := aggregator.New()
+// interruptContext returns a context that is cancelled on SIGINT or SIGTERM,
+// letting the running command save the cache before exiting
+// A second signal exits right away.
+func interruptContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		fmt.Fprintln(stderr, "Interrupted, saving cache (interrupt again to quit right away)")
+		cancel()
+		<-sigs
+		os.Exit(exitInterrupted)
+	}()
+	return ctx
+}
+
+// exitIfInterrupted saves the cache and exits if ctx was cancelled by a signal
+func exitIfInterrupted(ctx context.Context, agg *aggregator.Aggregator) {
+	if ctx.Err() == nil {
+		return
+	}
+	if progress != nil {
+		progress.Finish()
+	}
+	if err := agg.Cache.Save(); err != nil {
+		log.WithField("err", err).Error("Could not save cache")
+	}
+	os.Exit(exitInterrupted)
+}
+
+// loadCache returns the cache at cache.FileLocation, exiting if it cannot be
+// loaded
+func loadCache() *cache.Cache {
+	c, err := cache.New()
+	if err != nil {
+		exitCacheError(err)
+	}
+	return c
+}
+
+// loadAggregator returns an Aggregator without any clients, exiting if the
+// cache cannot be loaded
+func loadAggregator() *aggregator.Aggregator {
+	agg, err := aggregator.New()
+	if err != nil {
+		exitCacheError(err)
+	}
+	agg.OnProgress = onProgress
+	return agg
+}
+
+// exitCacheError explains why the cache cannot be used and exits
+func exitCacheError(err error) {
+	fmt.Fprintf(stderr, "Could not load cache: %s\n", err)
+	if errors.Is(err, cache.ErrCorrupt) {
+		fmt.Fprintln(stderr, "Remove the file or choose another one using --cache-file")
+	} else if errors.Is(err, cache.ErrNoLocation) {
+		fmt.Fprintln(stderr, "Choose a cache file using --cache-file")
+	}
+	os.Exit(exitCache)
+}
+
+// newAggregator returns an Aggregator with clients for all requested IDs,
+// friends, and group members
+func newAggregator(ctx context.Context, a *Arguments) *aggregator.Aggregator {
+	agg := loadAggregator()
 	for _, v := range *a.IDs {
+		exitIfInterrupted(ctx, agg)
 		log.WithField("id", v).Debug("Adding new ID to aggregator")
-		if err := agg.AddClient(v); err != nil {
-			log.WithField("err", err).Error("Could not add new client")
+		err := agg.AddClientContext(ctx, v)
+		if err != nil {
+			log.WithField("err", err).Debug("Could not add new client")
+			if !errors.Is(err, aggregator.ErrClientExists) {
+				recordError(err)
+			}
+		}
+		fmt.Fprintln(stderr, clientStatus(agg, v, err))
+	}
+	for _, v := range *a.FriendsOf {
+		log.WithField("id", v).Debug("Adding friends to aggregator")
+		n, err := agg.AddFriendsContext(ctx, v, *a.MaxFriends)
+		if err != nil {
+			log.WithField("err", err).Error("Could not add friends")
+			recordError(err)
+		}
+		log.WithFields(log.Fields{
+			"id":      v,
+			"friends": n,
+		}).Info("Added friends")
+	}
+	for _, v := range *a.Groups {
+		log.WithField("group", v).Debug("Adding group members to aggregator")
+		n, err := agg.AddGroupContext(ctx, v, *a.MaxMembers, *a.MemberConcurrency)
+		if err != nil {
+			log.WithField("err", err).Error("Could not add group members")
+			recordError(err)
+		}
+		log.WithFields(log.Fields{
+			"group":   v,
+			"members": n,
+		}).Info("Added group members")
+	}
+	exitIfInterrupted(ctx, agg)
+	return agg
+}
+
+func gameCommand(ctx context.Context, a *Arguments) {
+	log.WithField("subcmd", ".games").Debug("Subcommand entered")
+	agg := newAggregator(ctx, a)
+
+	if *a.Games.Wishlist {
+		if err := agg.UpdateWishlistsContext(ctx); err != nil {
+			log.WithField("err", err).Error("Could not fetch wishlists")
+			recordError(err)
 		}
+		exitIfInterrupted(ctx, agg)
 	}
 
 	if !*a.NoAutoCache {
-		err := agg.UpdateGameCache()
-		if err != nil {
+		report, err := agg.UpdateGameCacheContext(ctx)
+		if (err != nil) && (ctx.Err() == nil) {
 			log.WithField("err", err).Error("Could not update game cache")
+			recordError(err)
 		}
+		printReport("Game cache", report, retryGames)
+		exitIfInterrupted(ctx, agg)
+		recordError(report.Err)
 	}
 
 	if *a.Games.FetchTags {
-		err := agg.UpdateGameTags()
-		if err != nil {
+		report, err := agg.UpdateGameTagsContext(ctx)
+		if (err != nil) && (ctx.Err() == nil) {
 			log.WithField("err", err).Error("Could not fetch game tags")
+			recordError(err)
+		}
+		printReport("Game tags", report, retryTags)
+		exitIfInterrupted(ctx, agg)
+		recordError(report.Err)
+	}
+
+	if *a.Games.FetchReviews {
+		err := agg.UpdateGameReviewsContext(ctx)
+		if (err != nil) && (ctx.Err() == nil) {
+			log.WithField("err", err).Error("Could not fetch game reviews")
+			recordError(err)
 		}
+		exitIfInterrupted(ctx, agg)
 	}
 
-	games := agg.Select(*a.Games.Tag, *a.Games.Common, *a.Games.And, *a.Games.Invalid)
+	var games objects.JSONGameList
+	if *a.Games.Wishlist {
+		games = agg.SelectWishlist(*a.Games.Tag, *a.Games.Common, *a.Games.And, *a.Games.Invalid)
+	} else if *a.Games.MinOwners > 1 {
+		games = agg.SelectPopular(*a.Games.Tag, *a.Games.MinOwners, *a.Games.And, *a.Games.Invalid)
+	} else {
+		games = agg.Select(*a.Games.Tag, *a.Games.Common, *a.Games.And, *a.Games.Invalid)
+	}
+	games = games.Filter(&a.Games.Filter)
+	if len(*a.Games.WishlistedBy) > 0 {
+		games = wishlistedBy(ctx, agg, games, *a.Games.WishlistedBy)
+	}
+	if a.Games.MinCompletionFloat > 0 {
+		games = agg.FilterCompletionContext(ctx, games, a.Games.MinCompletionFloat)
+	}
+	exitIfInterrupted(ctx, agg)
 	log.WithField("games", len(games)).Debug("Selected games")
 
+	if *a.Games.Sort != "" {
+		if err := games.Sort(*a.Games.Sort, *a.Games.Reverse); err != nil {
+			log.WithField("err", err).Error("Could not sort games")
+			recordError(err)
+		}
+	}
+
 	if *a.Games.TagsOnly {
 		tags := games.AllTags()
 		log.WithField("tags", len(tags)).Debug("Selected all tags")
@@ -64,28 +263,275 @@ func gameCommand(a *Arguments) {
 		return
 	}
 
-	for _, g := range games {
-		var name string
-		if g.Invalid {
-			name = fmt.Sprintf("%s (INVALID)", g.Name)
-		} else {
-			name = g.Name
+	if *a.Games.Wishlist {
+		for _, g := range games {
+			fmt.Printf(
+				"%s : wished by %s\n",
+				formatGame(g, *a.Games.Field), strings.Join(agg.WishedBy(g.AppID), ", "),
+			)
+		}
+		return
+	}
+
+	if !*a.Games.IncludeDLC {
+		for _, g := range games {
+			fmt.Println(formatGame(g, *a.Games.Field))
+		}
+		return
+	}
+
+	for _, grp := range agg.Group(games) {
+		fmt.Println(formatGame(grp.Game, *a.Games.Field))
+		if len(grp.Game.DLC) > 0 {
+			owned := agg.DLCOwned(grp.Game)
+			counts := make([]string, 0, len(owned))
+			for id, n := range owned {
+				counts = append(counts, fmt.Sprintf(
+					"%s %d/%d", agg.Clients[id].Profile.SteamID, n, len(grp.Game.DLC),
+				))
+			}
+			sort.Strings(counts)
+			fmt.Printf("%10sDLC owned: %s\n", "", strings.Join(counts, ", "))
+		}
+		for _, dlc := range grp.DLC {
+			fmt.Printf("%10s+ %s\n", "", formatGame(dlc, *a.Games.Field))
+		}
+	}
+}
+
+func recentCommand(ctx context.Context, a *Arguments) {
+	log.WithField("subcmd", ".recent").Debug("Subcommand entered")
+	agg := newAggregator(ctx, a)
+	recent := agg.Recent()
+	log.WithField("games", len(recent)).Debug("Found recently played games")
+
+	ids := make([]int, 0, len(recent))
+	for _, rg := range recent {
+		ids = append(ids, rg.AppID)
+	}
+	if !*a.NoAutoCache {
+		report, err := agg.UpdateGamesContext(ctx, ids)
+		if (err != nil) && (ctx.Err() == nil) {
+			log.WithField("err", err).Error("Could not update game cache")
+			recordError(err)
+		}
+		printReport("Game cache", report, retryGames)
+		exitIfInterrupted(ctx, agg)
+		recordError(report.Err)
+	}
+	if *a.Recent.FetchTags {
+		report, err := agg.UpdateGameTagsContext(ctx)
+		if (err != nil) && (ctx.Err() == nil) {
+			log.WithField("err", err).Error("Could not fetch game tags")
+			recordError(err)
+		}
+		printReport("Game tags", report, retryTags)
+		exitIfInterrupted(ctx, agg)
+		recordError(report.Err)
+	}
+
+	selected := make(map[int]*objects.JSONGame)
+	for _, g := range agg.Cache.Games.Select(*a.Recent.Tag, ids, *a.Recent.And, *a.Recent.Invalid) {
+		selected[g.AppID] = g
+	}
+
+	for _, rg := range recent {
+		g, ok := selected[rg.AppID]
+		if !ok {
+			if _, cached := agg.Cache.Games.Get(rg.AppID); cached || (len(*a.Recent.Tag) > 0) {
+				continue // Filtered out
+			}
+			g = &objects.JSONGame{AppID: rg.AppID, Name: rg.Name}
 		}
+
+		split := make([]string, 0, len(rg.Split))
+		for id, h := range rg.Split {
+			split = append(split, fmt.Sprintf("%s %.1fh", agg.Clients[id].Profile.SteamID, h))
+		}
+		sort.Strings(split)
 		fmt.Printf(
-			"%-8s: %-40s : %s\n",
-			strconv.Itoa(g.AppID), name, strings.Join(g.CategoriesStrings(), ", "),
+			"%s : %.1fh (%s)\n",
+			formatGame(g, *a.Recent.Field), rg.Hours, strings.Join(split, ", "),
 		)
 	}
 }
 
-func cacheCommand(a *Arguments) {
+func achievementsCommand(ctx context.Context, a *Arguments) {
+	log.WithField("subcmd", ".achievements").Debug("Subcommand entered")
+	agg := newAggregator(ctx, a)
+	appid := *a.Achievements.AppID
+
+	progress := agg.AchievementsContext(ctx, appid)
+	exitIfInterrupted(ctx, agg)
+	if len(progress) < 1 {
+		fmt.Fprintf(stderr, "No achievement progress available for App ID %d\n", appid)
+		os.Exit(exitNoResult)
+	}
+
+	ids := make([]string, 0, len(progress))
+	for id := range progress {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var achievements []objects.XMLAchievement
+	name := ""
+	for _, id := range ids {
+		if len(progress[id].Achievements) > len(achievements) {
+			achievements = progress[id].Achievements
+		}
+		if name == "" {
+			name = progress[id].GameName
+		}
+	}
+
+	fmt.Printf("=== %s (%d) ===\n", name, appid)
+	for _, id := range ids {
+		s := progress[id]
+		fmt.Printf(
+			"%-40s : %d/%d (%.0f%%)\n",
+			agg.Clients[id].Profile.SteamID, s.Unlocked(), len(s.Achievements),
+			s.Completion(),
+		)
+	}
+	fmt.Println()
+
+	for _, ach := range achievements {
+		unlocked := make([]string, 0, len(ids))
+		for _, id := range ids {
+			for _, x := range progress[id].Achievements {
+				if (x.APIName == ach.APIName) && x.Unlocked {
+					unlocked = append(unlocked, agg.Clients[id].Profile.SteamID)
+					break
+				}
+			}
+		}
+		if *a.Achievements.Missing && (len(unlocked) == len(ids)) {
+			continue
+		}
+		if len(unlocked) < 1 {
+			unlocked = append(unlocked, "-")
+		}
+		fmt.Printf("%-40s : %s\n", ach.Name, strings.Join(unlocked, ", "))
+	}
+}
+
+// idCommand prints every representation of the IDs given after the command and
+// by --id, or of IDs read from stdin one per line if there are none
+func idCommand(ctx context.Context, a *Arguments) {
+	log.WithField("subcmd", ".id").Debug("Subcommand entered")
+	values := append(a.ID.Values, *a.IDs...)
+	if len(values) < 1 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if v := strings.TrimSpace(scanner.Text()); v != "" {
+				values = append(values, v)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.WithField("err", err).Error("Could not read stdin")
+			recordError(err)
+		}
+	}
+
+	infos := make([]*steamIDInfo, 0, len(values))
+	for _, v := range values {
+		info := newSteamIDInfo(ctx, v, *a.ID.Resolve)
+		if info.err != nil {
+			recordError(info.err)
+		}
+		infos = append(infos, info)
+	}
+
+	if *a.ID.JSON {
+		b, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			log.WithField("err", err).Fatal("Could not encode JSON")
+		}
+		fmt.Println(string(b))
+	} else {
+		for i, info := range infos {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(info)
+		}
+	}
+}
+
+// clientStatus returns a line describing whether the ID was added successfully
+func clientStatus(agg *aggregator.Aggregator, id string, err error) string {
+	var status string
+	switch {
+	case err == nil:
+		c := agg.Clients[id]
+		status = fmt.Sprintf("OK (%s, %d games)", c.Profile.SteamID, len(c.Profile.Games))
+	case errors.Is(err, profile.ErrProfileNotFound):
+		status = "profile not found"
+	case errors.Is(err, profile.ErrProfilePrivate):
+		status = "profile is private"
+	case errors.Is(err, profile.ErrGamesPrivate):
+		status = "game details are private"
+	default:
+		status = fmt.Sprintf("error: %s", err)
+	}
+	return fmt.Sprintf("%-20s: %s", id, status)
+}
+
+// wishlistedBy returns games from the list that are on the wishlist of any of
+// the specified profiles
+func wishlistedBy(ctx context.Context, agg *aggregator.Aggregator, games objects.JSONGameList, ids []string) objects.JSONGameList {
+	wanted := make(map[int]struct{})
+	for _, id := range ids {
+		w, err := agg.WishlistContext(ctx, id)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"id":  id,
+				"err": err,
+			}).Error("Could not retrieve wishlist")
+			recordError(err)
+			continue
+		}
+		for appid := range w {
+			wanted[appid] = struct{}{}
+		}
+	}
+
+	ret := make(objects.JSONGameList, 0, len(games))
+	for _, g := range games {
+		if _, ok := wanted[g.AppID]; ok {
+			ret = append(ret, g)
+		}
+	}
+	return ret
+}
+
+// The following hints are printed along with reports with failed games
+const (
+	retryGames = "Run 'steamcli cache games resume' to retry the failed games"
+	retryTags  = "Run again with --fetch-tags to retry the failed games"
+)
+
+// printReport prints the summary of an update of what to stderr unless nothing
+// had to be fetched, retry is printed if any games failed
+func printReport(what string, r *aggregator.UpdateReport, retry string) {
+	if (r.Fetched < 1) && (r.Failed < 1) {
+		return
+	}
+	fmt.Fprintf(stderr, "%s updated: %s\n", what, r)
+	if r.Failed > 0 {
+		fmt.Fprintln(stderr, retry)
+	}
+}
+
+func cacheCommand(ctx context.Context, a *Arguments) {
 	log.WithField("subcmd", ".cache").Debug("Subcommand entered")
 	if a.Cache.Games.Command.Happened() {
-		cacheGamesCommand(a)
+		cacheGamesCommand(ctx, a)
 	}
 }
 
-func cacheGamesCommand(a *Arguments) {
+func cacheGamesCommand(ctx context.Context, a *Arguments) {
 	log.WithField("subcmd", ".cache.games").Debug("Subcommand entered")
 	if a.Cache.Games.PurgeInvalid.Happened() {
 		cacheGamesPurgeInvalid(a)
@@ -97,37 +543,43 @@ func cacheGamesCommand(a *Arguments) {
 		cacheGamesPrint(a)
 	} else if a.Cache.Games.Delete.Command.Happened() {
 		cacheGamesDelete(a)
+	} else if a.Cache.Games.Resume.Happened() {
+		cacheGamesResume(ctx, a)
 	}
 }
 
 func cacheGamesPurgeInvalid(a *Arguments) {
-	c := cache.New()
+	c := loadCache()
 	n := c.Games.PurgeInvalid()
 	if err := c.Save(); err != nil {
 		log.WithField("err", err).Error("Could not save cache")
+		recordError(err)
 	}
 	fmt.Printf("Purged %d invalid games from cache\n", n)
 }
 
 func cacheGamesPurgeMissingTags(a *Arguments) {
-	c := cache.New()
+	c := loadCache()
 	n := c.Games.PurgeMissingTags()
 	if err := c.Save(); err != nil {
 		log.WithField("err", err).Error("Could not save cache")
+		recordError(err)
 	}
 	fmt.Printf("Purged %d games with missing tags from cache\n", n)
 }
 
 func cacheGamesInfo(a *Arguments) {
-	c := cache.New()
+	c := loadCache()
 
 	fmt.Println("=== Game Cache Information ===")
 	fmt.Printf("Total games: %d\n", len(c.Games))
 	fmt.Printf("Unique tags: %d\n", len(c.Games.AllTags()))
+	fmt.Printf("Outdated games: %d\n", c.Games.CountOutdated())
+	fmt.Printf("Pending games: %d\n", len(c.Pending))
 }
 
 func cacheGamesPrint(a *Arguments) {
-	c := cache.New()
+	c := loadCache()
 
 	games := c.Games.Select(
 		*a.Cache.Games.Print.Tag,
@@ -144,7 +596,7 @@ func cacheGamesPrint(a *Arguments) {
 }
 
 func cacheGamesDelete(a *Arguments) {
-	c := cache.New()
+	c := loadCache()
 
 	n := 0
 	for _, id := range a.Cache.Games.Delete.AppIDInt {
@@ -169,3 +621,21 @@ func cacheGamesDelete(a *Arguments) {
 		len(a.Cache.Games.Delete.AppIDInt)+len(*a.Cache.Games.Delete.Name),
 	)
 }
+
+func cacheGamesResume(ctx context.Context, a *Arguments) {
+	agg := loadAggregator()
+	pending := len(agg.Cache.PendingGames())
+	if pending < 1 {
+		fmt.Println("No pending games")
+		return
+	}
+
+	report, err := agg.ResumeGamesContext(ctx)
+	if (err != nil) && (ctx.Err() == nil) {
+		log.WithField("err", err).Error("Could not update game cache")
+		recordError(err)
+	}
+	fmt.Printf("Resumed %d pending games: %s\n", pending, report)
+	exitIfInterrupted(ctx, agg)
+	recordError(report.Err)
+}
@@ -0,0 +1,147 @@
+package objects
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The following constants describe the levels of controller support
+const (
+	ControllerFull    = "full"
+	ControllerPartial = "partial"
+)
+
+// The following constants describe how DLC is treated by GameFilter
+const (
+	DLCExclude = 0 // Only base games
+	DLCInclude = 1 // Base games and DLC
+	DLCOnly    = 2 // Only DLC
+)
+
+// GameFilter describes criteria based on store metadata a game has to match
+// Zero values are ignored, except for DLC which excludes DLC by default.
+type GameFilter struct {
+	ReleasedAfter  time.Time
+	ReleasedBefore time.Time
+	MinMetacritic  int
+	Controller     string   // ControllerPartial also matches full support
+	Genres         []string // Matches any of the genres
+	MinReviews     float64  // Minimum share of positive reviews (0-100)
+	DLC            int      // One of the DLC* constants
+}
+
+// GameSortKeys maps the names of sort keys to functions comparing two games
+var GameSortKeys = map[string]func(a, b *JSONGame) bool{
+	"name": func(a, b *JSONGame) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	},
+	"appid": func(a, b *JSONGame) bool {
+		return a.AppID < b.AppID
+	},
+	"released": func(a, b *JSONGame) bool {
+		return a.Released.Before(b.Released)
+	},
+	"metacritic": func(a, b *JSONGame) bool {
+		return a.Metacritic.Score < b.Metacritic.Score
+	},
+	"recommendations": func(a, b *JSONGame) bool {
+		return a.Recommendations.Total < b.Recommendations.Total
+	},
+	"reviews": func(a, b *JSONGame) bool {
+		return a.ReviewPercent() < b.ReviewPercent()
+	},
+}
+
+// Match determines whether the game fulfills all criteria of the filter
+func (f *GameFilter) Match(g *JSONGame) bool {
+	switch f.DLC {
+	case DLCExclude:
+		if g.IsDLC() {
+			return false
+		}
+	case DLCOnly:
+		if !g.IsDLC() {
+			return false
+		}
+	}
+
+	if !f.ReleasedAfter.IsZero() {
+		if g.Released.IsZero() || !g.Released.After(f.ReleasedAfter) {
+			return false
+		}
+	}
+	if !f.ReleasedBefore.IsZero() {
+		if g.Released.IsZero() || !g.Released.Before(f.ReleasedBefore) {
+			return false
+		}
+	}
+
+	if g.Metacritic.Score < f.MinMetacritic {
+		return false
+	}
+
+	if (f.MinReviews > 0) && (g.ReviewPercent() < f.MinReviews) {
+		return false
+	}
+
+	switch strings.ToLower(f.Controller) {
+	case "":
+	case ControllerPartial:
+		if (g.ControllerSupport != ControllerPartial) &&
+			(g.ControllerSupport != ControllerFull) {
+			return false
+		}
+	default:
+		if strings.ToLower(g.ControllerSupport) != strings.ToLower(f.Controller) {
+			return false
+		}
+	}
+
+	if len(f.Genres) > 0 {
+		found := false
+		for _, wanted := range f.Genres {
+			for _, genre := range g.Genres {
+				if strings.ToLower(genre.Description) == strings.ToLower(wanted) {
+					found = true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Filter returns games from the list matching the filter
+func (l *JSONGameList) Filter(f *GameFilter) JSONGameList {
+	ret := make(JSONGameList, 0, len(*l))
+	for _, g := range *l {
+		if f.Match(g) {
+			ret = append(ret, g)
+		}
+	}
+	return ret
+}
+
+// Sort orders the list in place using the specified key (see GameSortKeys)
+func (l *JSONGameList) Sort(key string, reverse bool) error {
+	less, ok := GameSortKeys[strings.ToLower(key)]
+	if !ok {
+		return fmt.Errorf("unknown sort key: '%s'", key)
+	}
+	sort.SliceStable(*l, func(i, j int) bool {
+		if reverse {
+			return less((*l)[j], (*l)[i])
+		}
+		return less((*l)[i], (*l)[j])
+	})
+	return nil
+}
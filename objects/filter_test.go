@@ -0,0 +1,126 @@
+package objects
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newGame returns a base game, or a DLC of base if it isn't 0
+func newGame(appid, base int) *JSONGame {
+	g := &JSONGame{AppID: appid, Type: "game"}
+	if base != 0 {
+		g.Type = "dlc"
+		g.FullGame.AppID = strconv.Itoa(base)
+	}
+	return g
+}
+
+func TestIsDLC(t *testing.T) {
+	soundtrack := &JSONGame{AppID: 3, Type: "music"}
+	soundtrack.FullGame.AppID = "1"
+	demo := &JSONGame{AppID: 4, Type: "demo"}
+	mislabelled := &JSONGame{AppID: 5, Type: "game"}
+	mislabelled.FullGame.AppID = "1"
+
+	tests := []struct {
+		name string
+		game *JSONGame
+		dlc  bool
+	}{
+		{"plain game", newGame(1, 0), false},
+		{"dlc type", newGame(2, 1), true},
+		{"dlc type without fullgame", &JSONGame{AppID: 2, Type: "dlc"}, true},
+		{"soundtrack with fullgame", soundtrack, true},
+		{"demo without fullgame", demo, false},
+		{"game with fullgame", mislabelled, false},
+	}
+	for _, tt := range tests {
+		if got := tt.game.IsDLC(); got != tt.dlc {
+			t.Errorf("%s: IsDLC() = %t, want %t", tt.name, got, tt.dlc)
+		}
+	}
+}
+
+func TestGameFilterDLC(t *testing.T) {
+	game, dlc := newGame(1, 0), newGame(2, 1)
+	tests := []struct {
+		name      string
+		dlc       int
+		matchGame bool
+		matchDLC  bool
+	}{
+		{"default", DLCExclude, true, false},
+		{"--include-dlc", DLCInclude, true, true},
+		{"--only-dlc", DLCOnly, false, true},
+	}
+	for _, tt := range tests {
+		f := &GameFilter{DLC: tt.dlc}
+		if got := f.Match(game); got != tt.matchGame {
+			t.Errorf("%s: Match(game) = %t, want %t", tt.name, got, tt.matchGame)
+		}
+		if got := f.Match(dlc); got != tt.matchDLC {
+			t.Errorf("%s: Match(dlc) = %t, want %t", tt.name, got, tt.matchDLC)
+		}
+	}
+}
+
+func TestGameFilterReleased(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		name     string
+		released string // Empty if unknown
+		filter   GameFilter
+		match    bool
+	}{
+		{"no bounds", "2010-06-01", GameFilter{}, true},
+		{"no bounds, unknown date", "", GameFilter{}, true},
+		{"after", "2010-06-01", GameFilter{ReleasedAfter: date("2010-01-01")}, true},
+		{"not after", "2009-06-01", GameFilter{ReleasedAfter: date("2010-01-01")}, false},
+		{"after is exclusive", "2010-01-01", GameFilter{ReleasedAfter: date("2010-01-01")}, false},
+		{"before", "2009-06-01", GameFilter{ReleasedBefore: date("2010-01-01")}, true},
+		{"not before", "2010-06-01", GameFilter{ReleasedBefore: date("2010-01-01")}, false},
+		{"between", "2010-06-01", GameFilter{ReleasedAfter: date("2010-01-01"), ReleasedBefore: date("2011-01-01")}, true},
+		{"outside", "2011-06-01", GameFilter{ReleasedAfter: date("2010-01-01"), ReleasedBefore: date("2011-01-01")}, false},
+		{"unknown date with bound", "", GameFilter{ReleasedAfter: date("2010-01-01")}, false},
+	}
+	for _, tt := range tests {
+		g := newGame(1, 0)
+		if tt.released != "" {
+			g.Released = date(tt.released)
+		}
+		if got := tt.filter.Match(g); got != tt.match {
+			t.Errorf("%s: Match() = %t, want %t", tt.name, got, tt.match)
+		}
+	}
+}
+
+func TestGameFilterMinReviews(t *testing.T) {
+	tests := []struct {
+		name    string
+		reviews *JSONGameReviews
+		min     float64
+		match   bool
+	}{
+		{"no minimum", nil, 0, true},
+		{"above", &JSONGameReviews{TotalPositive: 90, TotalNegative: 10}, 80, true},
+		{"equal", &JSONGameReviews{TotalPositive: 80, TotalNegative: 20}, 80, true},
+		{"below", &JSONGameReviews{TotalPositive: 70, TotalNegative: 30}, 80, false},
+		{"not fetched", nil, 80, false},
+		{"no reviews", &JSONGameReviews{}, 80, false},
+	}
+	for _, tt := range tests {
+		g := newGame(1, 0)
+		g.Reviews = tt.reviews
+		f := &GameFilter{MinReviews: tt.min}
+		if got := f.Match(g); got != tt.match {
+			t.Errorf("%s: Match() = %t, want %t", tt.name, got, tt.match)
+		}
+	}
+}
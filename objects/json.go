@@ -12,27 +12,20 @@ import (
 
 // JSONGame contains the details of a single game
 type JSONGame struct {
-	Invalid            bool        `json:"_is_invalid"` // Ignore AppID if invalid
-	Type               string      `json:"type"`
-	Name               string      `json:"name"`
-	AppID              int         `json:"steam_appid"`
-	RequiredAge        int         `json:"_required_age"`
-	RequiredAgeDummy   interface{} `json:"required_age"`
-	Description        string      `json:"detailed_description"`
-	DescriptionShort   string      `json:"short_description"`
-	SupportedLanguages string      `json:"supported_languages"`
-	Website            string      `json:"website"`
-	Developers         []string    `json:"developers"`
-	Publishers         []string    `json:"publishers"`
-	Price              struct {
-		Currency         string `json:"currency"`
-		Initial          int    `json:"initial"`
-		Final            int    `json:"final"`
-		DiscountPercent  int    `json:"discount_percent"`
-		InitialFormatted string `json:"initial_formatted"`
-		FinalFormatted   string `json:"final_formatted"`
-	} `json:"price_overview"`
-	Platforms struct {
+	Invalid            bool          `json:"_is_invalid"` // Ignore AppID if invalid
+	Type               string        `json:"type"`
+	Name               string        `json:"name"`
+	AppID              int           `json:"steam_appid"`
+	RequiredAge        int           `json:"_required_age"`
+	RequiredAgeDummy   interface{}   `json:"required_age"`
+	Description        string        `json:"detailed_description"`
+	DescriptionShort   string        `json:"short_description"`
+	SupportedLanguages string        `json:"supported_languages"`
+	Website            string        `json:"website"`
+	Developers         []string      `json:"developers"`
+	Publishers         []string      `json:"publishers"`
+	Price              JSONGamePrice `json:"price_overview"`
+	Platforms          struct {
 		Windows bool `json:"windows"`
 		Mac     bool `json:"mac"`
 		Linux   bool `json:"linux"`
@@ -41,15 +34,113 @@ type JSONGame struct {
 		ID          int    `json:"id"`
 		Description string `json:"description"`
 	} `json:"categories"`
-	Tags    []string `json:"tags"`
-	Updated time.Time
+	Genres []struct {
+		ID          string `json:"id"`
+		Description string `json:"description"`
+	} `json:"genres"`
+	ReleaseDate struct {
+		ComingSoon bool   `json:"coming_soon"`
+		Date       string `json:"date"`
+	} `json:"release_date"`
+	Released time.Time `json:"_released"`
+	DLC      []int     `json:"dlc"`
+	FullGame struct {
+		AppID string `json:"appid"`
+		Name  string `json:"name"`
+	} `json:"fullgame"`
+	Metacritic struct {
+		Score int    `json:"score"`
+		URL   string `json:"url"`
+	} `json:"metacritic"`
+	Recommendations struct {
+		Total int `json:"total"`
+	} `json:"recommendations"`
+	ControllerSupport  string `json:"controller_support"`
+	HeaderImage        string `json:"header_image"`
+	ContentDescriptors struct {
+		IDs   []int  `json:"ids"`
+		Notes string `json:"notes"`
+	} `json:"content_descriptors"`
+	PCRequirementsDummy    interface{}                     `json:"pc_requirements"`
+	MacRequirementsDummy   interface{}                     `json:"mac_requirements"`
+	LinuxRequirementsDummy interface{}                     `json:"linux_requirements"`
+	Requirements           map[string]JSONGameRequirements `json:"_requirements"` // Keyed by platform
+	Tags                   []string                        `json:"tags"`
+	Updated                time.Time
+
+	// Reviews is the user review summary, fetched separately from the details
+	Reviews *JSONGameReviews `json:"_reviews"`
+
+	// Schema is the JSONGameSchema version the game was fetched with
+	Schema int `json:"_schema"`
+
+	// Prices holds the price overview for every store region the game was
+	// fetched in, keyed by lowercase country code. A nil value means the
+	// game has no price in that region (e.g. free to play).
+	Prices map[string]*JSONGamePrice `json:"_prices"`
+}
+
+// JSONGamePrice contains the price of a game in a single store region
+type JSONGamePrice struct {
+	Currency         string `json:"currency"`
+	Initial          int    `json:"initial"`
+	Final            int    `json:"final"`
+	DiscountPercent  int    `json:"discount_percent"`
+	InitialFormatted string `json:"initial_formatted"`
+	FinalFormatted   string `json:"final_formatted"`
+}
+
+// JSONGameReviews contains the summary of a game's user reviews
+type JSONGameReviews struct {
+	Score            int    `json:"review_score"`
+	ScoreDescription string `json:"review_score_desc"`
+	TotalPositive    int    `json:"total_positive"`
+	TotalNegative    int    `json:"total_negative"`
+	TotalReviews     int    `json:"total_reviews"`
+	Updated          time.Time
+}
+
+// JSONGameRequirements contains the system requirements (HTML formatted) for
+// a single platform
+type JSONGameRequirements struct {
+	Minimum     string `json:"minimum"`
+	Recommended string `json:"recommended"`
+}
+
+// JSONGameSchema is the current version of the JSONGame structure
+// Cached games with an older version are missing fields and need refetching.
+const JSONGameSchema = 2
+
+// releaseDateFormats are the layouts the store uses for release dates,
+// depending on the language and on how precise the date is
+var releaseDateFormats = []string{
+	"2 Jan, 2006",
+	"Jan 2, 2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"January 2, 2006",
+	"Jan 2006",
+	"January 2006",
+	"2006",
 }
 
 // JSONGameList is a slice of games
 type JSONGameList []*JSONGame
 
+// JSONWishlistItem contains a single entry of a user's wishlist
+type JSONWishlistItem struct {
+	AppID    int   `json:"appid"`
+	Priority int   `json:"priority"`
+	Added    int64 `json:"date_added"` // Unix timestamp
+}
+
+// JSONWishlist is a map of a user's wishlisted games
+type JSONWishlist map[int]*JSONWishlistItem
+
 // Complete computes fields that cannot be determined automatically from JSON
-func (g *JSONGame) Complete() {
+// The country is the store region the data was requested for, the received
+// price is recorded under it.
+func (g *JSONGame) Complete(country string) {
 	// Fuck you Valve, why can't this be one type?
 	if x, ok := g.RequiredAgeDummy.(string); ok {
 		xx, err := strconv.Atoi(x)
@@ -65,6 +156,136 @@ func (g *JSONGame) Complete() {
 	if g.Updated.IsZero() {
 		g.Updated = time.Now()
 	}
+
+	if g.Released.IsZero() && !g.ReleaseDate.ComingSoon {
+		date := strings.TrimSpace(g.ReleaseDate.Date)
+		for _, f := range releaseDateFormats {
+			if t, err := time.Parse(f, date); err == nil {
+				g.Released = t
+				break
+			}
+		}
+		if g.Released.IsZero() && (date != "") {
+			log.WithFields(log.Fields{
+				"id":   g.AppID,
+				"date": date,
+			}).Debug("Couldn't parse release date")
+		}
+	}
+
+	// Valve sends an empty array instead of an object if there aren't any
+	if g.Requirements == nil {
+		g.Requirements = make(map[string]JSONGameRequirements)
+	}
+	for platform, dummy := range map[string]interface{}{
+		"pc":    g.PCRequirementsDummy,
+		"mac":   g.MacRequirementsDummy,
+		"linux": g.LinuxRequirementsDummy,
+	} {
+		if x, ok := dummy.(map[string]interface{}); ok {
+			r := JSONGameRequirements{}
+			r.Minimum, _ = x["minimum"].(string)
+			r.Recommended, _ = x["recommended"].(string)
+			g.Requirements[platform] = r
+		}
+	}
+
+	g.Schema = JSONGameSchema
+
+	if g.Prices == nil {
+		g.Prices = make(map[string]*JSONGamePrice)
+	}
+	if g.Price.Currency != "" {
+		price := g.Price
+		g.Prices[strings.ToLower(country)] = &price
+	} else {
+		g.Prices[strings.ToLower(country)] = nil
+	}
+}
+
+// Outdated determines whether the game was cached with an older schema
+func (g *JSONGame) Outdated() bool {
+	return g.Schema < JSONGameSchema
+}
+
+// FullGameID returns the App ID of the base game, 0 if there is none
+func (g *JSONGame) FullGameID() int {
+	id, err := strconv.Atoi(g.FullGame.AppID)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// IsDLC determines whether the app is an add-on (DLC, soundtrack, ...) for
+// another game
+func (g *JSONGame) IsDLC() bool {
+	if g.Type == "dlc" {
+		return true
+	}
+	return (g.Type != "game") && (g.FullGameID() != 0)
+}
+
+// GenresStrings returns a slice of all genre descriptions
+func (g *JSONGame) GenresStrings() []string {
+	ret := make([]string, 0, len(g.Genres))
+	for _, x := range g.Genres {
+		ret = append(ret, x.Description)
+	}
+	return ret
+}
+
+// HasRegion determines whether the game's price was fetched for the country
+func (g *JSONGame) HasRegion(country string) bool {
+	_, ok := g.Prices[strings.ToLower(country)]
+	return ok
+}
+
+// PriceFor returns the price of the game in the specified store region
+// Returns nil if the game has no price there or wasn't fetched for it.
+func (g *JSONGame) PriceFor(country string) *JSONGamePrice {
+	return g.Prices[strings.ToLower(country)]
+}
+
+// Merge copies regional prices, tags, and reviews from an older copy of the game that
+// aren't present in this one
+func (g *JSONGame) Merge(old *JSONGame) {
+	if old == nil {
+		return
+	}
+	if g.Prices == nil {
+		g.Prices = make(map[string]*JSONGamePrice)
+	}
+	for cc, p := range old.Prices {
+		if _, ok := g.Prices[cc]; !ok {
+			g.Prices[cc] = p
+		}
+	}
+	if g.Tags == nil {
+		g.Tags = old.Tags
+	}
+	if g.Reviews == nil {
+		g.Reviews = old.Reviews
+	}
+}
+
+// Percent returns the share of positive reviews (0-100)
+// Returns -1 if there aren't any reviews.
+func (r *JSONGameReviews) Percent() float64 {
+	total := r.TotalPositive + r.TotalNegative
+	if total < 1 {
+		return -1
+	}
+	return float64(r.TotalPositive) * 100 / float64(total)
+}
+
+// ReviewPercent returns the share of positive reviews of the game (0-100)
+// Returns -1 if the reviews weren't fetched or there aren't any.
+func (g *JSONGame) ReviewPercent() float64 {
+	if g.Reviews == nil {
+		return -1
+	}
+	return g.Reviews.Percent()
 }
 
 // CategoriesStrings returns a slice of all category descriptions
@@ -2,7 +2,12 @@ package objects
 
 import (
 	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+
+	"gitlab.com/vultour/steamcli/steamerr"
 )
 
 // XMLProfileError contains an error message associated with the request
@@ -25,7 +30,10 @@ type XMLProfile struct {
 	MemberSinceString string   `xml:"memberSince" json:"memberSince"`
 	Location          string   `xml:"location" json:"location"`
 	MemberSince       time.Time
-	Games             XMLGameMap `json:"games"`
+	Games             XMLGameMap              `xml:"-" json:"games"`
+	Wishlist          JSONWishlist            `xml:"-" json:"wishlist"`     // nil if not fetched
+	Friends           []int64                 `xml:"-" json:"friends"`      // nil if not fetched
+	Achievements      map[int]*XMLPlayerStats `xml:"-" json:"achievements"` // Keyed by App ID
 	Updated           time.Time
 }
 
@@ -37,7 +45,7 @@ type XMLProfileGame struct {
 	LinkStore        string   `xml:"storeLink" json:"-"`
 	LinkStats        string   `xml:"statsLink" json:"-"`
 	LinksStatsGlobal string   `xml:"globalStatsLink" json:"-"`
-	PlaytimeTwoWeeks string   `xml:"hoursLast2Weeks" json:"-"`
+	PlaytimeTwoWeeks string   `xml:"hoursLast2Weeks" json:"playtime_2weeks,omitempty"`
 	PlaytimeTotal    string   `xml:"hoursOnRecord" json:"playtime_total"`
 }
 
@@ -50,17 +58,61 @@ type XMLProfileGames struct {
 	Games   []XMLProfileGame `xml:"games>game" json:"games"`
 }
 
-// Complete computes additional fields in SteamProfile
-func (profile *XMLProfile) Complete() {
-	var err error
+// XMLPlayerStats contains a user's achievement progress in a single game
+type XMLPlayerStats struct {
+	XMLName      xml.Name         `xml:"playerstats" json:"-"`
+	AppID        int              `xml:"-" json:"appID"`
+	GameName     string           `xml:"game>gameName" json:"gameName"`
+	Achievements []XMLAchievement `xml:"achievements>achievement" json:"achievements"`
+	Updated      time.Time
+	Unavailable  bool `xml:"-" json:"unavailable,omitempty"` // The game has no stats page
+}
 
-	if profile.MemberSince.IsZero() {
-		profile.MemberSince, err = time.Parse("January 2, 2006", profile.MemberSinceString)
-		if err != nil {
-			panic(err)
-		}
-	}
+// XMLAchievement contains a single achievement and whether it was unlocked
+type XMLAchievement struct {
+	Unlocked        bool   `xml:"closed,attr" json:"unlocked"`
+	APIName         string `xml:"apiname" json:"apiname"`
+	Name            string `xml:"name" json:"name"`
+	Description     string `xml:"description" json:"description"`
+	UnlockTimestamp int64  `xml:"unlockTimestamp" json:"unlockTimestamp"`
+}
+
+// XMLProfileFriends contains the user's public friends list
+type XMLProfileFriends struct {
+	XMLName   xml.Name `xml:"friendsList" json:"-"`
+	SteamID64 int64    `xml:"steamID64" json:"steamID64"`
+	Friends   []int64  `xml:"friends>friend" json:"friends"`
+}
+
+// XMLGroup contains information about a community group and its members
+// A response only contains one page of members, Members accumulates them.
+type XMLGroup struct {
+	XMLName     xml.Name `xml:"memberList" json:"-"`
+	GroupID64   int64    `xml:"groupID64" json:"groupID64"`
+	Name        string   `xml:"groupDetails>groupName" json:"groupName"`
+	URL         string   `xml:"groupDetails>groupURL" json:"groupURL"`
+	MemberCount int      `xml:"memberCount" json:"memberCount"`
+	TotalPages  int      `xml:"totalPages" json:"-"`
+	CurrentPage int      `xml:"currentPage" json:"-"`
+	Members     []int64  `xml:"members>steamID64" json:"members"`
+	Updated     time.Time
+}
+
+// The following constants describe the values of XMLProfile.PrivacyState
+const (
+	VisibilityPrivate     = 1
+	VisibilityFriendsOnly = 2
+	VisibilityPublic      = 3
+)
 
+// ErrInvalidDate is returned by XMLProfile.Complete when the member since date
+// cannot be parsed
+var ErrInvalidDate = steamerr.New(steamerr.ErrDecode, "invalid member since date")
+
+// Complete computes additional fields in SteamProfile
+// All fields are computed even if an error is returned, the error only means
+// MemberSince is unknown and left zero.
+func (profile *XMLProfile) Complete() error {
 	if profile.Updated.IsZero() {
 		profile.Updated = time.Now()
 	}
@@ -68,6 +120,34 @@ func (profile *XMLProfile) Complete() {
 	if profile.Games == nil {
 		profile.Games = make(map[int]*XMLProfileGame)
 	}
+
+	if profile.MemberSince.IsZero() {
+		t, err := time.Parse("January 2, 2006", profile.MemberSinceString)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidDate, profile.MemberSinceString)
+		}
+		profile.MemberSince = t
+	}
+	return nil
+}
+
+// HoursTwoWeeks returns the hours played in the last two weeks
+func (g *XMLProfileGame) HoursTwoWeeks() float64 {
+	return parseHours(g.PlaytimeTwoWeeks)
+}
+
+// HoursTotal returns the hours played overall
+func (g *XMLProfileGame) HoursTotal() float64 {
+	return parseHours(g.PlaytimeTotal)
+}
+
+// parseHours parses the hours as formatted by the community (e.g. "1,234.5")
+func parseHours(s string) float64 {
+	h, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(s), ",", "", -1), 64)
+	if err != nil {
+		return 0
+	}
+	return h
 }
 
 // Contains determines whether the specified appid exists in the game list
@@ -85,3 +165,23 @@ func (g *XMLGameMap) ContainsByName(game string) bool {
 	}
 	return false
 }
+
+// Unlocked returns the number of unlocked achievements
+func (s *XMLPlayerStats) Unlocked() int {
+	n := 0
+	for _, a := range s.Achievements {
+		if a.Unlocked {
+			n++
+		}
+	}
+	return n
+}
+
+// Completion returns the share of unlocked achievements (0-100)
+// Returns -1 if the game has no achievements.
+func (s *XMLPlayerStats) Completion() float64 {
+	if len(s.Achievements) < 1 {
+		return -1
+	}
+	return float64(s.Unlocked()) * 100 / float64(len(s.Achievements))
+}
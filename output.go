@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gitlab.com/vultour/steamcli/aggregator"
+	"gitlab.com/vultour/steamcli/objects"
+)
+
+// gameFields maps output field names to functions rendering them
+var gameFields = map[string]func(g *objects.JSONGame) string{
+	"appid": func(g *objects.JSONGame) string {
+		return strconv.Itoa(g.AppID)
+	},
+	"name": func(g *objects.JSONGame) string {
+		if g.Invalid {
+			return fmt.Sprintf("%s (INVALID)", g.Name)
+		}
+		return g.Name
+	},
+	"type": func(g *objects.JSONGame) string {
+		return g.Type
+	},
+	"categories": func(g *objects.JSONGame) string {
+		return strings.Join(g.CategoriesStrings(), ", ")
+	},
+	"genres": func(g *objects.JSONGame) string {
+		return strings.Join(g.GenresStrings(), ", ")
+	},
+	"tags": func(g *objects.JSONGame) string {
+		return strings.Join(g.Tags, ", ")
+	},
+	"developers": func(g *objects.JSONGame) string {
+		return strings.Join(g.Developers, ", ")
+	},
+	"publishers": func(g *objects.JSONGame) string {
+		return strings.Join(g.Publishers, ", ")
+	},
+	"released": func(g *objects.JSONGame) string {
+		if g.Released.IsZero() {
+			return g.ReleaseDate.Date
+		}
+		return g.Released.Format("2006-01-02")
+	},
+	"metacritic": func(g *objects.JSONGame) string {
+		return strconv.Itoa(g.Metacritic.Score)
+	},
+	"recommendations": func(g *objects.JSONGame) string {
+		return strconv.Itoa(g.Recommendations.Total)
+	},
+	"reviews": func(g *objects.JSONGame) string {
+		if g.Reviews == nil {
+			return ""
+		}
+		if g.ReviewPercent() < 0 {
+			return g.Reviews.ScoreDescription
+		}
+		return fmt.Sprintf(
+			"%s (%.0f%% of %d)",
+			g.Reviews.ScoreDescription, g.ReviewPercent(), g.Reviews.TotalReviews,
+		)
+	},
+	"controller": func(g *objects.JSONGame) string {
+		return g.ControllerSupport
+	},
+	"header": func(g *objects.JSONGame) string {
+		return g.HeaderImage
+	},
+	"price": func(g *objects.JSONGame) string {
+		if g.Prices == nil { // Cached before regional prices existed
+			return g.Price.FinalFormatted
+		}
+		if p := g.PriceFor(aggregator.Country); p != nil {
+			return p.FinalFormatted
+		}
+		return ""
+	},
+}
+
+// defaultGameFields are printed when no fields were requested
+var defaultGameFields = []string{"appid", "name", "categories"}
+
+// gameFieldWidths pads some of the fields to keep the columns aligned
+var gameFieldWidths = map[string]int{
+	"appid": 7,
+	"name":  40,
+}
+
+func validateFields(fields []string) error {
+	for _, f := range fields {
+		if _, ok := gameFields[strings.ToLower(f)]; !ok {
+			return fmt.Errorf("Unknown output field: '%s'", f)
+		}
+	}
+	return nil
+}
+
+func formatGame(g *objects.JSONGame, fields []string) string {
+	if len(fields) < 1 {
+		fields = defaultGameFields
+	}
+	values := make([]string, 0, len(fields))
+	for i, f := range fields {
+		f = strings.ToLower(f)
+		v := gameFields[f](g)
+		if w, ok := gameFieldWidths[f]; ok && (i < len(fields)-1) {
+			v = fmt.Sprintf("%-*s", w, v)
+		}
+		values = append(values, v)
+	}
+	return strings.Join(values, " : ")
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/vultour/steamcli/aggregator"
+)
+
+const (
+	progressWidth = 80                     // Of the whole line
+	progressBarW  = 20                     // Of the bar itself
+	progressEvery = 100 * time.Millisecond // Minimum time between redraws
+)
+
+// progressBar draws the progress of aggregator updates on the last line of a
+// terminal, anything written to it is printed above the bar
+type progressBar struct {
+	w    io.Writer
+	mu   sync.Mutex
+	line string // Currently drawn, empty if none
+	last time.Time
+}
+
+// newProgressBar returns a progress bar drawn on f, or nil if f isn't a
+// terminal
+func newProgressBar(f *os.File) *progressBar {
+	fi, err := f.Stat()
+	if (err != nil) || (fi.Mode()&os.ModeCharDevice == 0) {
+		return nil
+	}
+	return &progressBar{w: f}
+}
+
+// Update redraws the bar, it's an aggregator.ProgressFunc
+// The line is finished once everything is done, nothing is drawn if there's
+// nothing to do.
+func (b *progressBar) Update(p aggregator.Progress) {
+	if p.Total < 1 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	done := p.Done >= p.Total
+	if !done && (p.Done > 0) && (time.Since(b.last) < progressEvery) {
+		return
+	}
+	b.last = time.Now()
+	b.draw(formatProgress(p))
+	if done {
+		fmt.Fprintln(b.w)
+		b.line = ""
+	}
+}
+
+// Write prints p above the bar, making the bar usable as the log output
+func (b *progressBar) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	line := b.line
+	if line != "" {
+		fmt.Fprint(b.w, "\r\033[K")
+	}
+	n, err := b.w.Write(p)
+	if line != "" {
+		fmt.Fprint(b.w, line)
+	}
+	return n, err
+}
+
+// Finish ends the line of an unfinished bar, e.g. after an interruption
+func (b *progressBar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.line != "" {
+		fmt.Fprintln(b.w)
+		b.line = ""
+	}
+}
+
+func (b *progressBar) draw(line string) {
+	fmt.Fprint(b.w, "\r\033[K", line)
+	b.line = line
+}
+
+// formatProgress renders the progress as a single line, e.g.
+// "games   [=====>              ] 12/48 1 failed 0.9/s ETA 40s Portal 2"
+func formatProgress(p aggregator.Progress) string {
+	filled := 0
+	if p.Total > 0 {
+		filled = progressBarW * p.Done / p.Total
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarW {
+		bar += ">" + strings.Repeat(" ", progressBarW-filled-1)
+	}
+
+	s := fmt.Sprintf("%-7s [%s] %d/%d", p.Task, bar, p.Done, p.Total)
+	if p.Failed > 0 {
+		s += fmt.Sprintf(" %d failed", p.Failed)
+	}
+	if p.Done > 0 {
+		s += fmt.Sprintf(" %.1f/s", p.Rate())
+		if p.Done < p.Total {
+			s += fmt.Sprintf(" ETA %s", p.ETA().Round(time.Second))
+		}
+	}
+	if p.Name != "" {
+		s += " " + p.Name
+	} else if p.Current != 0 {
+		s += fmt.Sprintf(" %d", p.Current)
+	}
+
+	if r := []rune(s); len(r) > progressWidth {
+		s = string(r[:progressWidth-3]) + "..."
+	}
+	return s
+}
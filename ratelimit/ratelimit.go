@@ -0,0 +1,232 @@
+// Package ratelimit spaces out requests made to each host and retries the ones
+// that were rate limited or failed transiently
+package ratelimit
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Limit describes how often requests can be made to one host
+type Limit struct {
+	Every time.Duration // One request per Every on average, 0 for no limit
+	Burst int           // Requests that can be made at once after idling
+}
+
+// DefaultLimits are the limits used by Default
+// The store allows roughly 200 appdetails requests per five minutes.
+var DefaultLimits = map[string]Limit{
+	"store.steampowered.com": {Every: 600 * time.Millisecond, Burst: 1},
+	"steamcommunity.com":     {Every: 250 * time.Millisecond, Burst: 4},
+}
+
+// Default is the limiter shared by all API clients
+var Default = New(DefaultLimits)
+
+// maxSlowdown caps how much a host's limit is stretched after it rate limits
+const maxSlowdown = 16
+
+// Limiter hands out permission to make requests using a token bucket per host
+// A host that signals rate limiting is paused for the backoff and its limit is
+// stretched, successful requests relax it back over time.
+type Limiter struct {
+	// The following fields describe how failed requests are retried, change
+	// them before using the Limiter
+	MaxRetries  int           // Retries per request
+	BaseBackoff time.Duration // Doubled with every retry
+	MaxBackoff  time.Duration // Also caps Retry-After
+
+	mu      sync.Mutex
+	limits  map[string]Limit
+	buckets map[string]*bucket
+}
+
+// bucket is the token bucket of a single host
+type bucket struct {
+	limit    Limit
+	tokens   float64
+	last     time.Time
+	paused   time.Time // No requests are let through until then
+	slowdown float64   // Multiplies limit.Every, at least 1
+}
+
+// New returns a Limiter using the limits keyed by host, hosts without a limit
+// are only subject to backoff
+func New(limits map[string]Limit) *Limiter {
+	l := &Limiter{
+		MaxRetries:  8,
+		BaseBackoff: 2 * time.Second,
+		MaxBackoff:  5 * time.Minute,
+		limits:      make(map[string]Limit),
+		buckets:     make(map[string]*bucket),
+	}
+	for host, limit := range limits {
+		l.limits[host] = limit
+	}
+	return l
+}
+
+// SetLimit changes the limit of the host
+func (l *Limiter) SetLimit(host string, limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits[host] = limit
+	if b, ok := l.buckets[host]; ok {
+		b.limit = limit
+	}
+}
+
+// Wait blocks until a request can be made to the host
+func (l *Limiter) Wait(host string) {
+	l.WaitContext(context.Background(), host)
+}
+
+// WaitContext is like Wait but gives up once ctx is done, returning its error
+func (l *Limiter) WaitContext(ctx context.Context, host string) error {
+	for {
+		d := l.reserve(host, time.Now())
+		if d <= 0 {
+			return nil
+		}
+		log.WithFields(log.Fields{
+			"host": host,
+			"wait": d,
+		}).Debug("Throttling request")
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep pauses for d or until ctx is done, returning its error in that case
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the host's bucket, returning how long to wait
+// before trying again if there isn't one
+func (l *Limiter) reserve(host string, now time.Time) time.Duration {
+	b := l.bucket(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(b.paused) {
+		return b.paused.Sub(now)
+	}
+
+	every := time.Duration(float64(b.limit.Every) * b.slowdown)
+	if every <= 0 {
+		return 0
+	}
+	burst := float64(b.limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	b.tokens += float64(now.Sub(b.last)) / float64(every)
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) * float64(every))
+}
+
+// bucket returns the host's bucket, creating it if needed
+func (l *Limiter) bucket(host string) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{
+			limit:    l.limits[host],
+			tokens:   float64(l.limits[host].Burst),
+			last:     time.Now(),
+			slowdown: 1,
+		}
+		l.buckets[host] = b
+	}
+	return b
+}
+
+// Backoff pauses all requests to the host for d and stretches its limit
+func (l *Limiter) Backoff(host string, d time.Duration) {
+	b := l.bucket(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(b.paused) {
+		b.paused = until
+	}
+	b.slowdown *= 2
+	if b.slowdown > maxSlowdown {
+		b.slowdown = maxSlowdown
+	}
+	log.WithFields(log.Fields{
+		"host":     host,
+		"pause":    d,
+		"slowdown": b.slowdown,
+	}).Warn("Rate limited, slowing down")
+}
+
+// Success relaxes the host's limit after a successful request
+func (l *Limiter) Success(host string) {
+	b := l.bucket(host)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b.slowdown > 1 {
+		b.slowdown *= 0.9
+		if b.slowdown < 1 {
+			b.slowdown = 1
+		}
+	}
+}
+
+// backoff returns the wait before the given retry (counted from 0), doubling
+// the base backoff every time and adding up to 50% jitter
+func (l *Limiter) backoff(retry int) time.Duration {
+	d := l.BaseBackoff << uint(retry)
+	if (d <= 0) || (d > l.MaxBackoff) {
+		d = l.MaxBackoff
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// retryAfter returns the wait requested by the Retry-After header, either in
+// seconds or as a date, and false if there's none
+func (l *Limiter) retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if s, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(s) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if d > l.MaxBackoff {
+		d = l.MaxBackoff
+	}
+	return d, true
+}
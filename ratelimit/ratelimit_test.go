@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// newBucket returns a limiter with a full bucket for the host, last refilled
+// at now
+func newBucket(limit Limit, now time.Time) *Limiter {
+	l := New(map[string]Limit{"example.com": limit})
+	b := l.bucket("example.com")
+	b.tokens = float64(limit.Burst)
+	b.last = now
+	return l
+}
+
+func TestReserve(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		limit Limit
+		after []time.Duration // When the requests are made, since now
+		wait  []time.Duration // What reserve returns for them
+	}{
+		{
+			"burst then one per interval",
+			Limit{Every: 100 * time.Millisecond, Burst: 2},
+			[]time.Duration{0, 0, 0, 50 * time.Millisecond, 100 * time.Millisecond},
+			[]time.Duration{0, 0, 100 * time.Millisecond, 50 * time.Millisecond, 0},
+		},
+		{
+			"refill is capped at the burst",
+			Limit{Every: 100 * time.Millisecond, Burst: 2},
+			[]time.Duration{time.Second, time.Second, time.Second},
+			[]time.Duration{0, 0, 100 * time.Millisecond},
+		},
+		{
+			"refill up to one without a burst",
+			Limit{Every: 100 * time.Millisecond},
+			[]time.Duration{0, 100 * time.Millisecond, 100 * time.Millisecond, time.Second, time.Second},
+			[]time.Duration{100 * time.Millisecond, 0, 100 * time.Millisecond, 0, 100 * time.Millisecond},
+		},
+		{
+			"no limit",
+			Limit{},
+			[]time.Duration{0, 0, 0},
+			[]time.Duration{0, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		l := newBucket(tt.limit, now)
+		for i, after := range tt.after {
+			if got := l.reserve("example.com", now.Add(after)); got != tt.wait[i] {
+				t.Errorf("%s: request %d waits %s, want %s", tt.name, i, got, tt.wait[i])
+			}
+		}
+	}
+}
+
+func TestBackoffSlowdown(t *testing.T) {
+	tests := []struct {
+		ops      string // b for Backoff, s for Success
+		slowdown float64
+	}{
+		{"", 1},
+		{"s", 1},
+		{"b", 2},
+		{"bb", 4},
+		{"bbbbbbbb", maxSlowdown},
+		{"bs", 1.8},
+		{"bbs", 3.6},
+		{"bssssssss", 1},
+	}
+	for _, tt := range tests {
+		l := New(nil)
+		for _, op := range tt.ops {
+			if op == 'b' {
+				l.Backoff("example.com", 0)
+			} else {
+				l.Success("example.com")
+			}
+		}
+		if got := l.bucket("example.com").slowdown; (got < tt.slowdown-1e-9) || (got > tt.slowdown+1e-9) {
+			t.Errorf("%q: slowdown = %g, want %g", tt.ops, got, tt.slowdown)
+		}
+	}
+}
+
+func TestBackoffPause(t *testing.T) {
+	now := time.Now()
+	l := newBucket(Limit{Every: 100 * time.Millisecond, Burst: 1}, now)
+	l.Backoff("example.com", time.Hour)
+
+	// Requests wait for the pause
+	if d := l.reserve("example.com", time.Now()); (d < 59*time.Minute) || (d > time.Hour) {
+		t.Errorf("reserve() during the pause = %s, want about an hour", d)
+	}
+	// A shorter backoff doesn't shorten the pause
+	l.Backoff("example.com", time.Second)
+	if d := l.reserve("example.com", time.Now()); d < 59*time.Minute {
+		t.Errorf("reserve() after a shorter backoff = %s, want about an hour", d)
+	}
+	// The limit is stretched once the pause is over
+	after := now.Add(2 * time.Hour)
+	l.reserve("example.com", after)
+	if d := l.reserve("example.com", after); d != 400*time.Millisecond {
+		t.Errorf("reserve() after the pause = %s, want %s", d, 400*time.Millisecond)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	l := New(nil)
+	l.MaxBackoff = time.Minute
+	date := func(d time.Duration) string {
+		return time.Now().Add(d).UTC().Format(http.TimeFormat)
+	}
+	tests := []struct {
+		header   string
+		min, max time.Duration
+		ok       bool
+	}{
+		{"", 0, 0, false},
+		{"5", 5 * time.Second, 5 * time.Second, true},
+		{"0", 0, 0, true},
+		{"-3", 0, 0, true},
+		{"3600", time.Minute, time.Minute, true}, // Capped
+		{"soon", 0, 0, false},
+		{date(30 * time.Second), 28 * time.Second, 30 * time.Second, true},
+		{date(-time.Hour), 0, 0, true},
+		{date(time.Hour), time.Minute, time.Minute, true}, // Capped
+	}
+	for _, tt := range tests {
+		h := make(http.Header)
+		if tt.header != "" {
+			h.Set("Retry-After", tt.header)
+		}
+		d, ok := l.retryAfter(h)
+		if (ok != tt.ok) || (d < tt.min) || (d > tt.max) {
+			t.Errorf("retryAfter(%q) = %s, %t, want %s-%s, %t", tt.header, d, ok, tt.min, tt.max, tt.ok)
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	l := New(nil)
+	l.BaseBackoff = time.Millisecond
+	l.MaxBackoff = 10 * time.Millisecond
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{0, time.Millisecond, 1500 * time.Microsecond},
+		{2, 4 * time.Millisecond, 6 * time.Millisecond},
+		{5, 10 * time.Millisecond, 15 * time.Millisecond},  // Capped
+		{70, 10 * time.Millisecond, 15 * time.Millisecond}, // Overflows
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := l.backoff(tt.retry); (d < tt.min) || (d > tt.max) {
+				t.Errorf("backoff(%d) = %s, want %s-%s", tt.retry, d, tt.min, tt.max)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"gitlab.com/vultour/steamcli/steamerr"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// nullPeek is how much of a response body is read to detect a "null" response
+const nullPeek = 16
+
+// Transport is an http.RoundTripper waiting for the Limiter before every
+// request and retrying requests that failed with a timeout, HTTP 429, a 5xx
+// status, or a "null" body (the store's way of saying slow down)
+// Only requests without a body are retried. Once the retries run out a
+// steamerr.ErrRateLimited error is returned if the host kept rate limiting,
+// otherwise the last response or error. Waiting stops once the request's
+// context is done.
+type Transport struct {
+	Base    http.RoundTripper // nil for http.DefaultTransport
+	Limiter *Limiter
+	Timeout time.Duration // Per attempt, including reading the body; 0 for none
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	host := req.URL.Hostname()
+	retryable := (req.Body == nil) || (req.Body == http.NoBody)
+
+	for retry := 0; ; retry++ {
+		if err := t.Limiter.WaitContext(req.Context(), host); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.attempt(base, req)
+		wait, limited, again := t.classify(req, resp, err)
+		if again && limited && retryable && (retry >= t.Limiter.MaxRetries) {
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, steamerr.New(
+				steamerr.ErrRateLimited,
+				fmt.Sprintf("%s kept rate limiting after %d retries", host, retry),
+			)
+		}
+		if !again || !retryable || (retry >= t.Limiter.MaxRetries) {
+			if (err == nil) && !again {
+				t.Limiter.Success(host)
+			}
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		if wait <= 0 {
+			wait = t.Limiter.backoff(retry)
+		}
+		log.WithFields(log.Fields{
+			"url":   req.URL.Host + req.URL.Path, // The query may contain an API key
+			"retry": retry + 1,
+			"wait":  wait,
+			"err":   err,
+		}).Info("Retrying request")
+		if limited {
+			t.Limiter.Backoff(host, wait)
+		} else if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt performs a single request, bounded by the Timeout
+func (t *Transport) attempt(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if t.Timeout <= 0 {
+		return base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.Timeout)
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// classify determines whether the attempt should be retried, how long to
+// wait if the server asked for it, and whether the host rate limited
+func (t *Transport) classify(req *http.Request, resp *http.Response, err error) (time.Duration, bool, bool) {
+	if err != nil {
+		// Only our own timeouts, cancelling the request shouldn't retry it
+		var netErr net.Error
+		timeout := errors.Is(err, context.DeadlineExceeded) ||
+			(errors.As(err, &netErr) && netErr.Timeout())
+		return 0, false, timeout && (req.Context().Err() == nil)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		wait, _ := t.Limiter.retryAfter(resp.Header)
+		return wait, true, true
+	case resp.StatusCode >= 500:
+		wait, _ := t.Limiter.retryAfter(resp.Header)
+		return wait, false, true
+	case (resp.StatusCode == http.StatusOK) && isNull(resp):
+		return 0, true, true
+	}
+	return 0, false, false
+}
+
+// isNull determines whether the body is "null", the body stays readable
+func isNull(resp *http.Response) bool {
+	if resp.ContentLength > nullPeek {
+		return false
+	}
+	peek := make([]byte, nullPeek+1)
+	n, err := io.ReadFull(resp.Body, peek)
+	peek = peek[:n]
+	resp.Body = &readCloser{
+		Reader: io.MultiReader(bytes.NewReader(peek), resp.Body),
+		Closer: resp.Body,
+	}
+	if (err != io.ErrUnexpectedEOF) && (err != io.EOF) {
+		return false // Longer than nullPeek or failed
+	}
+	return string(bytes.TrimSpace(peek)) == "null"
+}
+
+// cancelBody releases the context of an attempt once the body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
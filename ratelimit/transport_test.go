@@ -0,0 +1,228 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gitlab.com/vultour/steamcli/steamerr"
+)
+
+// script is a server answering its requests with the responses in order,
+// repeating the last one
+type script struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses []func(w http.ResponseWriter)
+	attempts  int
+}
+
+func newScript(responses ...func(w http.ResponseWriter)) *script {
+	s := &script{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		i := s.attempts
+		if i >= len(s.responses) {
+			i = len(s.responses) - 1
+		}
+		s.attempts++
+		s.mu.Unlock()
+		s.responses[i](w)
+	}))
+	return s
+}
+
+func (s *script) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts
+}
+
+func status(code int) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(code)
+	}
+}
+
+func body(b string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Write([]byte(b))
+	}
+}
+
+// chunked writes the body without a Content-Length
+func chunked(b string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		w.Write([]byte(b))
+	}
+}
+
+func newTransport() *Transport {
+	l := New(nil)
+	l.MaxRetries = 2
+	l.BaseBackoff = time.Millisecond
+	l.MaxBackoff = 10 * time.Millisecond // Also caps Retry-After
+	return &Transport{Limiter: l}
+}
+
+func TestIsNull(t *testing.T) {
+	long := strings.Repeat("x", 2*nullPeek)
+	tests := []struct {
+		name     string
+		response func(w http.ResponseWriter)
+		body     string
+		null     bool
+	}{
+		{"null", body("null"), "null", true},
+		{"null with whitespace", body(" null\n"), " null\n", true},
+		{"chunked null", chunked("null"), "null", true},
+		{"json", body(`{"10":{}}`), `{"10":{}}`, false},
+		{"empty", body(""), "", false},
+		{"long", body(long), long, false},
+		{"chunked long", chunked(long), long, false},
+		{"chunked long null prefix", chunked("null" + long), "null" + long, false},
+	}
+	for _, tt := range tests {
+		s := newScript(tt.response)
+		resp, err := http.Get(s.URL)
+		if err != nil {
+			t.Fatalf("%s: GET = %v", tt.name, err)
+		}
+		if got := isNull(resp); got != tt.null {
+			t.Errorf("%s: isNull() = %t, want %t", tt.name, got, tt.null)
+		}
+		b, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if (err != nil) || (string(b) != tt.body) {
+			t.Errorf("%s: body after isNull() = %q, %v, want %q", tt.name, b, err, tt.body)
+		}
+		s.Close()
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		responses []func(w http.ResponseWriter)
+		status    int   // Of the returned response
+		err       error // Instead of a response
+		attempts  int
+	}{
+		{"success", http.MethodGet, nil, http.StatusOK, nil, 1},
+		{"429", http.MethodGet, []func(http.ResponseWriter){status(429)}, http.StatusOK, nil, 2},
+		{"503", http.MethodGet, []func(http.ResponseWriter){status(503)}, http.StatusOK, nil, 2},
+		{"null", http.MethodGet, []func(http.ResponseWriter){body("null"), body("null")}, http.StatusOK, nil, 3},
+		{"404", http.MethodGet, []func(http.ResponseWriter){status(404)}, http.StatusNotFound, nil, 1},
+		{"keeps rate limiting", http.MethodGet, []func(http.ResponseWriter){status(429), status(429), status(429)}, 0, steamerr.ErrRateLimited, 3},
+		{"keeps returning null", http.MethodGet, []func(http.ResponseWriter){body("null"), body("null"), body("null")}, 0, steamerr.ErrRateLimited, 3},
+		{"keeps failing", http.MethodGet, []func(http.ResponseWriter){status(500), status(500), status(500)}, http.StatusInternalServerError, nil, 3},
+		{"with a body", http.MethodPost, []func(http.ResponseWriter){status(429)}, http.StatusTooManyRequests, nil, 1},
+	}
+	for _, tt := range tests {
+		s := newScript(append(tt.responses, body("ok"))...)
+		var reqBody io.Reader
+		if tt.method == http.MethodPost {
+			reqBody = strings.NewReader("appids=10")
+		}
+		req, err := http.NewRequest(tt.method, s.URL, reqBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resp, err := newTransport().RoundTrip(req)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: RoundTrip() = %v, want %v", tt.name, err, tt.err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: RoundTrip() = %v", tt.name, err)
+		} else {
+			if resp.StatusCode != tt.status {
+				t.Errorf("%s: RoundTrip() status = %d, want %d", tt.name, resp.StatusCode, tt.status)
+			}
+			resp.Body.Close()
+		}
+		if n := s.count(); n != tt.attempts {
+			t.Errorf("%s: made %d attempts, want %d", tt.name, n, tt.attempts)
+		}
+		s.Close()
+	}
+}
+
+func TestRoundTripBody(t *testing.T) {
+	s := newScript(body("null"), body(`{"10":{"success":true}}`))
+	defer s.Close()
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := newTransport().RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() = %v", err)
+	}
+	defer resp.Body.Close()
+	if b, err := ioutil.ReadAll(resp.Body); (err != nil) || (string(b) != `{"10":{"success":true}}`) {
+		t.Errorf("RoundTrip() body = %q, %v", b, err)
+	}
+}
+
+func TestRoundTripCancel(t *testing.T) {
+	s := newScript(status(429))
+	defer s.Close()
+	tr := newTransport()
+	tr.Limiter.MaxRetries = 100
+	tr.Limiter.MaxBackoff = time.Minute // Retry-After pauses for a second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := tr.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RoundTrip() = %v, want the deadline", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("RoundTrip() kept waiting for %s after the deadline", d)
+	}
+	if n := s.count(); n != 1 {
+		t.Errorf("made %d attempts, want 1", n)
+	}
+}
+
+func TestRoundTripTimeout(t *testing.T) {
+	slow := func(w http.ResponseWriter) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("late"))
+	}
+	s := newScript(slow, body("ok"))
+	defer s.Close()
+	tr := newTransport()
+	tr.Timeout = 20 * time.Millisecond
+
+	req, err := http.NewRequest(http.MethodGet, s.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() = %v, want the attempt to be retried", err)
+	}
+	defer resp.Body.Close()
+	if b, _ := ioutil.ReadAll(resp.Body); string(b) != "ok" {
+		t.Errorf("RoundTrip() body = %q, want the retried response", b)
+	}
+}
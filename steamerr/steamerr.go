@@ -0,0 +1,74 @@
+// Package steamerr defines the classes of errors returned by the steamcli
+// packages
+//
+// Every error returned by the API clients, the aggregator, the cache, and the
+// id package belongs to one of the classes below, test for them using
+// errors.Is. The more specific sentinels of those packages (e.g.
+// profile.ErrProfilePrivate) keep working as well.
+package steamerr
+
+import (
+	"errors"
+)
+
+// The following errors are the classes
+var (
+	ErrNetwork     = errors.New("network error")       // Request failed or timed out
+	ErrRateLimited = errors.New("rate limited")        // Retries ran out while rate limited
+	ErrNotFound    = errors.New("not found")           // Profile, group, game, or stats don't exist
+	ErrPrivate     = errors.New("private")             // Profile or its details aren't public
+	ErrDecode      = errors.New("unexpected response") // Response couldn't be decoded
+	ErrCache       = errors.New("cache unusable")      // Cache file couldn't be read, decoded, or written
+	ErrInvalid     = errors.New("invalid input")       // e.g. an unparseable Steam ID
+)
+
+// classes are checked by ClassOf, more specific ones first
+var classes = []error{
+	ErrCache, ErrRateLimited, ErrPrivate, ErrNotFound, ErrDecode, ErrNetwork, ErrInvalid,
+}
+
+// Error is an error belonging to a class
+type Error struct {
+	Class error  // One of the Err* classes
+	Msg   string // What failed
+	Err   error  // The underlying error, may be nil
+}
+
+// New returns an error of the class, e.g. for a package level sentinel
+func New(class error, msg string) error {
+	return &Error{Class: class, Msg: msg}
+}
+
+// Wrap returns an error of the class describing what failed because of err
+func Wrap(class error, msg string, err error) error {
+	return &Error{Class: class, Msg: msg, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Msg
+	}
+	return e.Msg + ": " + e.Err.Error()
+}
+
+// Is reports whether target is the class of the error
+func (e *Error) Is(target error) bool {
+	return target == e.Class
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ClassOf returns the class of the error, nil if it doesn't belong to any
+// If the error matches several classes (e.g. a rate limited request is also a
+// network error), the most specific one is returned.
+func ClassOf(err error) error {
+	for _, c := range classes {
+		if errors.Is(err, c) {
+			return c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package steamerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassOf(t *testing.T) {
+	plain := errors.New("plain")
+	tests := []struct {
+		name  string
+		err   error
+		class error
+	}{
+		{"nil", nil, nil},
+		{"unclassified", plain, nil},
+		{"class", ErrPrivate, ErrPrivate},
+		{"new", New(ErrNotFound, "profile not found"), ErrNotFound},
+		{"wrap", Wrap(ErrDecode, "couldn't decode json", plain), ErrDecode},
+		{"fmt wrap", fmt.Errorf("app 10: %w", New(ErrDecode, "game missing")), ErrDecode},
+		{"rate limited network error", Wrap(ErrNetwork, "request failed", New(ErrRateLimited, "kept rate limiting")), ErrRateLimited},
+		{"network error wrapping invalid input", Wrap(ErrNetwork, "request failed", New(ErrInvalid, "bad url")), ErrNetwork},
+		{"cache wins", Wrap(ErrDecode, "couldn't decode", New(ErrCache, "cache unusable")), ErrCache},
+		{"private wins over not found", Wrap(ErrNotFound, "stats", New(ErrPrivate, "profile is private")), ErrPrivate},
+	}
+	for _, tt := range tests {
+		if got := ClassOf(tt.err); got != tt.class {
+			t.Errorf("%s: ClassOf(%v) = %v, want %v", tt.name, tt.err, got, tt.class)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(ErrNetwork, "could not retrieve data", cause)
+	if got, want := err.Error(), "could not retrieve data: connection refused"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(err, cause) || !errors.Is(err, ErrNetwork) || errors.Is(err, ErrDecode) {
+		t.Errorf("errors.Is doesn't see the cause and only the class of %v", err)
+	}
+	if got := New(ErrInvalid, "bad id").Error(); got != "bad id" {
+		t.Errorf("Error() without a cause = %q", got)
+	}
+}
@@ -0,0 +1,609 @@
+// Package steamtest provides a fake Steam server for testing code built on the
+// steamcli packages without hitting Steam
+//
+// The server answers the community profile, games, friends, achievements and
+// group member XML, the store appdetails and appreviews JSON, the store HTML
+// pages used for tags, and the Web API methods used by the profile package
+// from in-memory fixtures. Point the API clients at it using Use or Settings.
+package steamtest
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/objects"
+	"gitlab.com/vultour/steamcli/ratelimit"
+)
+
+// Server is a fake Steam Community and Store
+// Fixtures and simulated failures can be changed while the server is running.
+type Server struct {
+	*httptest.Server
+
+	// Limiter is used by the settings pointing at the server, it retries
+	// quickly so simulated failures don't slow tests down
+	Limiter *ratelimit.Limiter
+
+	mu       sync.Mutex
+	profiles map[int64]*Profile
+	aliases  map[string]int64 // Custom URL names to 64bit IDs
+	apps     map[int]*App
+	groups   map[int64]*Group
+	groupURL map[string]int64 // Group URL names to 64bit IDs
+	requests []string
+
+	delay         time.Duration
+	nullResponses int
+	rateLimited   int
+}
+
+// Profile is a community profile fixture
+type Profile struct {
+	SteamID64      int64
+	Name           string
+	CustomURL      string // Makes the profile available under /id/
+	MemberSince    time.Time
+	Private        bool // The whole profile is private
+	GamesPrivate   bool // Only the game details are private
+	FriendsPrivate bool // Only the friends list is private
+	Games          []Game
+	Friends        []int64
+	Wishlist       []int // App IDs, empty if the profile is private
+}
+
+// Game is a game owned by a Profile
+type Game struct {
+	AppID         int
+	Name          string
+	HoursTotal    float64
+	HoursTwoWeeks float64
+	Achievements  []Achievement // nil if the game has no stats page
+}
+
+// Achievement is an achievement of a Game, as the owner progressed in it
+type Achievement struct {
+	APIName  string
+	Name     string
+	Unlocked bool
+}
+
+// App is a store fixture
+type App struct {
+	AppID       int
+	Name        string
+	Type        string // "game" if empty
+	Tags        []string
+	Unavailable bool // appdetails returns success:false
+
+	// UnavailableIn lists the country codes for which appdetails returns
+	// success:false
+	UnavailableIn []string
+
+	// Positive and Negative are the numbers of user reviews served by
+	// appreviews
+	Positive int
+	Negative int
+
+	// Details are added to the appdetails data as they are, e.g.
+	// "release_date" or "metacritic"
+	Details map[string]interface{}
+}
+
+// Group is a community group fixture
+type Group struct {
+	GroupID64 int64
+	Name      string
+	URL       string // Makes the group available under /groups/
+	Members   []int64
+	PageSize  int // Members per page of the member list, 1000 if 0
+}
+
+// NewServer starts a server without any fixtures, stop it using Close
+func NewServer() *Server {
+	s := &Server{
+		profiles: make(map[int64]*Profile),
+		aliases:  make(map[string]int64),
+		apps:     make(map[int]*App),
+		groups:   make(map[int64]*Group),
+		groupURL: make(map[string]int64),
+		Limiter:  ratelimit.New(nil),
+	}
+	s.Limiter.BaseBackoff = time.Millisecond
+	s.Limiter.MaxBackoff = 10 * time.Millisecond
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Settings returns endpoint settings pointing every Steam service at the
+// server
+// Only the transport is set so the clients keep their timeouts.
+func (s *Server) Settings() endpoints.Settings {
+	return endpoints.Settings{
+		Community: s.URL,
+		Store:     s.URL,
+		WebAPI:    s.URL,
+		Transport: s.Client().Transport,
+		Limiter:   s.Limiter,
+	}
+}
+
+// Use points endpoints.Config at the server, the returned function restores
+// the previous configuration
+func (s *Server) Use() func() {
+	old := endpoints.Config
+	endpoints.Config = s.Settings()
+	return func() {
+		endpoints.Config = old
+	}
+}
+
+// AddProfile adds or replaces a profile fixture
+func (s *Server) AddProfile(p *Profile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles[p.SteamID64] = p
+	if p.CustomURL != "" {
+		s.aliases[strings.ToLower(p.CustomURL)] = p.SteamID64
+	}
+}
+
+// AddApp adds or replaces a store fixture
+func (s *Server) AddApp(a *App) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apps[a.AppID] = a
+}
+
+// AddGroup adds or replaces a group fixture
+func (s *Server) AddGroup(g *Group) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[g.GroupID64] = g
+	if g.URL != "" {
+		s.groupURL[strings.ToLower(g.URL)] = g.GroupID64
+	}
+}
+
+// SetDelay makes every response wait for d before being written, e.g. to
+// trigger client timeouts
+func (s *Server) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delay = d
+}
+
+// SetNullResponses makes the next n appdetails requests return null the way
+// the store does when rate limiting
+func (s *Server) SetNullResponses(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nullResponses = n
+}
+
+// SetRateLimited makes the next n requests fail with HTTP 429 and a
+// Retry-After of one second
+func (s *Server) SetRateLimited(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimited = n
+}
+
+// Requests returns the path and query of every request received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	delay := s.delay
+	limited := s.rateLimited > 0
+	if limited {
+		s.rateLimited--
+	}
+	s.mu.Unlock()
+	if limited {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case (len(parts) >= 2) && (parts[0] == endpoints.ID):
+		id, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			writeXMLError(w, "The specified profile could not be found.")
+			return
+		}
+		s.serveProfile(w, id, parts[2:])
+	case (len(parts) >= 2) && (parts[0] == endpoints.Alias):
+		s.mu.Lock()
+		id, ok := s.aliases[strings.ToLower(parts[1])]
+		s.mu.Unlock()
+		if !ok {
+			writeXMLError(w, "The specified profile could not be found.")
+			return
+		}
+		s.serveProfile(w, id, parts[2:])
+	case (len(parts) >= 3) && (parts[0] == endpoints.GroupID) && (parts[2] == endpoints.GroupMember):
+		id, _ := strconv.ParseInt(parts[1], 10, 64)
+		s.serveGroup(w, id, r.URL.Query().Get("p"))
+	case (len(parts) >= 3) && (parts[0] == endpoints.Group) && (parts[2] == endpoints.GroupMember):
+		s.mu.Lock()
+		id := s.groupURL[strings.ToLower(parts[1])]
+		s.mu.Unlock()
+		s.serveGroup(w, id, r.URL.Query().Get("p"))
+	case strings.Trim(r.URL.Path, "/") == endpoints.AppDetails:
+		s.serveAppDetails(w, strings.Split(r.URL.Query().Get("appids"), ","), r.URL.Query().Get("cc"))
+	case strings.Trim(r.URL.Path, "/") == endpoints.GetWishlist:
+		s.serveWishlist(w, r.URL.Query().Get("steamid"))
+	case isKeyMethod(strings.Trim(r.URL.Path, "/")):
+		s.serveWebAPI(w, r)
+	case (len(parts) >= 2) && (parts[0] == endpoints.AppReviews):
+		appid, _ := strconv.Atoi(parts[1])
+		s.serveReviews(w, appid)
+	case (len(parts) >= 2) && (parts[0] == endpoints.App):
+		appid, err := strconv.Atoi(parts[1])
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveAppPage(w, appid)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveProfile writes the profile XML or the XML of the sub-page in path
+func (s *Server) serveProfile(w http.ResponseWriter, id int64, path []string) {
+	s.mu.Lock()
+	p, ok := s.profiles[id]
+	s.mu.Unlock()
+	if !ok {
+		writeXMLError(w, "The specified profile could not be found.")
+		return
+	}
+
+	if len(path) < 1 {
+		writeXML(w, newXMLProfile(p))
+		return
+	}
+
+	switch path[0] {
+	case endpoints.Games:
+		if p.Private || p.GamesPrivate {
+			writeXMLError(w, "This profile is private.")
+			return
+		}
+		games := objects.XMLProfileGames{}
+		for _, g := range p.Games {
+			game := objects.XMLProfileGame{
+				Name:          g.Name,
+				AppID:         g.AppID,
+				PlaytimeTotal: strconv.FormatFloat(g.HoursTotal, 'f', 1, 64),
+			}
+			if g.HoursTwoWeeks > 0 {
+				game.PlaytimeTwoWeeks = strconv.FormatFloat(g.HoursTwoWeeks, 'f', 1, 64)
+			}
+			games.Games = append(games.Games, game)
+		}
+		writeXML(w, games)
+	case endpoints.Friends:
+		if p.Private || p.FriendsPrivate {
+			writeXMLError(w, "This profile is private.")
+			return
+		}
+		writeXML(w, objects.XMLProfileFriends{SteamID64: p.SteamID64, Friends: p.Friends})
+	case endpoints.Stats:
+		appid := 0
+		if len(path) > 1 {
+			appid, _ = strconv.Atoi(path[1])
+		}
+		s.serveStats(w, p, appid)
+	default:
+		writeXMLError(w, "The requested page could not be found.")
+	}
+}
+
+// xmlPlayerStats is the achievements XML as Steam formats it
+type xmlPlayerStats struct {
+	XMLName      xml.Name         `xml:"playerstats"`
+	GameName     string           `xml:"game>gameName"`
+	Achievements []xmlAchievement `xml:"achievements>achievement"`
+}
+
+type xmlAchievement struct {
+	Closed          int    `xml:"closed,attr"`
+	APIName         string `xml:"apiname"`
+	Name            string `xml:"name"`
+	Description     string `xml:"description"`
+	UnlockTimestamp int64  `xml:"unlockTimestamp,omitempty"`
+}
+
+// serveStats writes the achievements of the profile in the game
+// Games that aren't owned or don't have stats get the error Steam returns.
+func (s *Server) serveStats(w http.ResponseWriter, p *Profile, appid int) {
+	if p.Private || p.GamesPrivate {
+		writeXMLError(w, "This profile is private.")
+		return
+	}
+	for _, g := range p.Games {
+		if (g.AppID != appid) || (g.Achievements == nil) {
+			continue
+		}
+		stats := xmlPlayerStats{GameName: g.Name}
+		for _, a := range g.Achievements {
+			x := xmlAchievement{APIName: a.APIName, Name: a.Name}
+			if a.Unlocked {
+				x.Closed = 1
+				x.UnlockTimestamp = 1500000000
+			}
+			stats.Achievements = append(stats.Achievements, x)
+		}
+		writeXML(w, stats)
+		return
+	}
+	writeXMLError(w, "The requested profile does not have stats for this game.")
+}
+
+// xmlGroup is one page of the group member list as Steam formats it
+type xmlGroup struct {
+	XMLName     xml.Name `xml:"memberList"`
+	GroupID64   int64    `xml:"groupID64"`
+	Name        string   `xml:"groupDetails>groupName"`
+	URL         string   `xml:"groupDetails>groupURL"`
+	MemberCount int      `xml:"memberCount"`
+	TotalPages  int      `xml:"totalPages"`
+	CurrentPage int      `xml:"currentPage"`
+	Starting    int      `xml:"startingMember"`
+	Members     []int64  `xml:"members>steamID64"`
+}
+
+// serveGroup writes the page of the group member list, the first page if
+// page isn't a number
+func (s *Server) serveGroup(w http.ResponseWriter, id int64, page string) {
+	s.mu.Lock()
+	g, ok := s.groups[id]
+	s.mu.Unlock()
+	if !ok {
+		writeXMLError(w, "No group could be retrieved for the given URL.")
+		return
+	}
+
+	size := g.PageSize
+	if size < 1 {
+		size = 1000
+	}
+	pages := (len(g.Members) + size - 1) / size
+	if pages < 1 {
+		pages = 1
+	}
+	current, err := strconv.Atoi(page)
+	if (err != nil) || (current < 1) {
+		current = 1
+	}
+	ret := xmlGroup{
+		GroupID64:   g.GroupID64,
+		Name:        g.Name,
+		URL:         g.URL,
+		MemberCount: len(g.Members),
+		TotalPages:  pages,
+		CurrentPage: current,
+		Starting:    (current - 1) * size,
+	}
+	if ret.Starting < len(g.Members) {
+		end := ret.Starting + size
+		if end > len(g.Members) {
+			end = len(g.Members)
+		}
+		ret.Members = g.Members[ret.Starting:end]
+	}
+	writeXML(w, ret)
+}
+
+// xmlProfile is the subset of the profile XML understood by the profile
+// package, as Steam formats it
+type xmlProfile struct {
+	XMLName      xml.Name `xml:"profile"`
+	SteamID64    int64    `xml:"steamID64"`
+	SteamID      string   `xml:"steamID"`
+	CustomURL    string   `xml:"customURL"`
+	Privacy      string   `xml:"privacyState"`
+	PrivacyState int      `xml:"visibilityState"`
+	MemberSince  string   `xml:"memberSince,omitempty"`
+}
+
+func newXMLProfile(p *Profile) *xmlProfile {
+	ret := &xmlProfile{
+		SteamID64:    p.SteamID64,
+		SteamID:      p.Name,
+		CustomURL:    p.CustomURL,
+		Privacy:      "public",
+		PrivacyState: objects.VisibilityPublic,
+	}
+	if p.Private {
+		ret.Privacy = "private"
+		ret.PrivacyState = objects.VisibilityPrivate
+		return ret // Private profiles don't show the member since date
+	}
+	since := p.MemberSince
+	if since.IsZero() {
+		since = time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+	ret.MemberSince = since.Format("January 2, 2006")
+	return ret
+}
+
+// serveAppDetails writes the appdetails JSON for the apps in the country
+func (s *Server) serveAppDetails(w http.ResponseWriter, appids []string, cc string) {
+	s.mu.Lock()
+	null := s.nullResponses > 0
+	if null {
+		s.nullResponses--
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if null {
+		fmt.Fprint(w, "null")
+		return
+	}
+
+	ret := make(map[string]interface{})
+	for _, v := range appids {
+		appid, err := strconv.Atoi(v)
+		s.mu.Lock()
+		a, ok := s.apps[appid]
+		s.mu.Unlock()
+		if (err != nil) || !ok || a.Unavailable || a.unavailableIn(cc) {
+			ret[v] = map[string]interface{}{"success": false}
+			continue
+		}
+
+		data := map[string]interface{}{
+			"type":        "game",
+			"name":        a.Name,
+			"steam_appid": a.AppID,
+		}
+		if a.Type != "" {
+			data["type"] = a.Type
+		}
+		for k, d := range a.Details {
+			data[k] = d
+		}
+		ret[v] = map[string]interface{}{"success": true, "data": data}
+	}
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (a *App) unavailableIn(cc string) bool {
+	for _, c := range a.UnavailableIn {
+		if strings.EqualFold(c, cc) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveReviews writes the appreviews summary of the app, apps that aren't
+// available don't have one
+func (s *Server) serveReviews(w http.ResponseWriter, appid int) {
+	s.mu.Lock()
+	a, ok := s.apps[appid]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ok || a.Unavailable {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": 2})
+		return
+	}
+	score, desc := reviewScore(a.Positive, a.Negative)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": 1,
+		"query_summary": map[string]interface{}{
+			"num_reviews":       0,
+			"review_score":      score,
+			"review_score_desc": desc,
+			"total_positive":    a.Positive,
+			"total_negative":    a.Negative,
+			"total_reviews":     a.Positive + a.Negative,
+		},
+	})
+}
+
+// reviewScore returns the score and its description the store shows for the
+// reviews, roughly
+func reviewScore(positive, negative int) (int, string) {
+	total := positive + negative
+	switch {
+	case total < 1:
+		return 0, "No user reviews"
+	case positive*100 >= total*80:
+		return 8, "Very Positive"
+	case positive*100 >= total*70:
+		return 6, "Mostly Positive"
+	case positive*100 >= total*40:
+		return 5, "Mixed"
+	}
+	return 4, "Mostly Negative"
+}
+
+// serveWishlist writes the Web API wishlist of the profile, unknown and
+// private profiles get an empty response like they do from Steam
+func (s *Server) serveWishlist(w http.ResponseWriter, steamid string) {
+	id, _ := strconv.ParseInt(steamid, 10, 64)
+	s.mu.Lock()
+	p, ok := s.profiles[id]
+	s.mu.Unlock()
+
+	response := make(map[string]interface{})
+	if ok && !p.Private && (len(p.Wishlist) > 0) {
+		items := make([]map[string]interface{}, 0, len(p.Wishlist))
+		for i, appid := range p.Wishlist {
+			items = append(items, map[string]interface{}{
+				"appid":      appid,
+				"priority":   i + 1,
+				"date_added": 1500000000 + i,
+			})
+		}
+		response["items"] = items
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"response": response})
+}
+
+// serveAppPage writes a store page containing the app's tags
+// Unknown apps redirect to the store front like Steam does.
+func (s *Server) serveAppPage(w http.ResponseWriter, appid int) {
+	s.mu.Lock()
+	a, ok := s.apps[appid]
+	s.mu.Unlock()
+	if !ok || a.Unavailable {
+		w.Header().Set("Location", s.URL+"/")
+		w.WriteHeader(http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	fmt.Fprintf(w, "<html><head><title>%s on Steam</title>\n", html.EscapeString(a.Name))
+	fmt.Fprintf(w, "<meta property=\"og:url\" content=\"%s/%s/%d/\">\n", s.URL, endpoints.App, appid)
+	fmt.Fprint(w, "</head><body><div class=\"glance_tags popular_tags\">\n")
+	for _, t := range a.Tags {
+		fmt.Fprintf(w, "<a href=\"#\" class=\"app_tag\">\n\t%s\n</a>\n", html.EscapeString(t))
+	}
+	fmt.Fprint(w, "</div></body></html>\n")
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(v)
+}
+
+// writeXMLError writes the response the community XML endpoints use for
+// errors
+func writeXMLError(w http.ResponseWriter, msg string) {
+	writeXML(w, struct {
+		XMLName xml.Name `xml:"response"`
+		Error   string   `xml:"error"`
+	}{Error: msg})
+}
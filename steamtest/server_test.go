@@ -0,0 +1,183 @@
+package steamtest
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// get requests the path from the server without retrying
+func get(t *testing.T, s *Server, path string) (int, string) {
+	t.Helper()
+	r, err := s.Client().Get(s.URL + path)
+	if err != nil {
+		t.Fatalf("GET %s = %v", path, err)
+	}
+	defer r.Body.Close()
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.Fatalf("reading %s = %v", path, err)
+	}
+	return r.StatusCode, string(b)
+}
+
+// details returns whether appdetails succeeded for every app in the response
+func details(t *testing.T, body string) map[string]bool {
+	t.Helper()
+	obj := map[string]struct {
+		Success bool `json:"success"`
+	}{}
+	if err := json.Unmarshal([]byte(body), &obj); err != nil {
+		t.Fatalf("decoding %q = %v", body, err)
+	}
+	ret := make(map[string]bool)
+	for k, v := range obj {
+		ret[k] = v.Success
+	}
+	return ret
+}
+
+func TestNullResponses(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddApp(&App{AppID: 10, Name: "Counter-Strike"})
+	s.SetNullResponses(2)
+
+	for i := 0; i < 2; i++ {
+		if _, body := get(t, s, "/api/appdetails?appids=10"); body != "null" {
+			t.Errorf("request %d = %q, want null", i, body)
+		}
+	}
+	if _, body := get(t, s, "/api/appdetails?appids=10"); !details(t, body)["10"] {
+		t.Errorf("request after the null responses = %q", body)
+	}
+}
+
+func TestUnavailableApps(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddApp(&App{AppID: 10, Name: "Counter-Strike"})
+	s.AddApp(&App{AppID: 20, Name: "Delisted", Unavailable: true})
+	s.AddApp(&App{AppID: 30, Name: "Region Locked", UnavailableIn: []string{"de"}})
+
+	tests := []struct {
+		query string
+		want  map[string]bool
+	}{
+		{"appids=10,20,30,40", map[string]bool{"10": true, "20": false, "30": true, "40": false}},
+		{"appids=10,30&cc=us", map[string]bool{"10": true, "30": true}},
+		{"appids=10,30&cc=DE", map[string]bool{"10": true, "30": false}},
+	}
+	for _, tt := range tests {
+		_, body := get(t, s, "/api/appdetails?"+tt.query)
+		got := details(t, body)
+		for id, ok := range tt.want {
+			if got[id] != ok {
+				t.Errorf("%s: app %s success = %t, want %t", tt.query, id, got[id], ok)
+			}
+		}
+	}
+
+	// The store pages of unavailable apps redirect to the front page
+	r, err := http.NewRequest(http.MethodGet, s.URL+"/app/20/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusFound {
+		t.Errorf("store page of an unavailable app = %d, want a redirect", response.StatusCode)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddProfile(&Profile{SteamID64: 76561197960287930, Name: "player"})
+	s.SetRateLimited(1)
+
+	r, err := s.Client().Get(s.URL + "/profiles/76561197960287930/?xml=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Body.Close()
+	if (r.StatusCode != http.StatusTooManyRequests) || (r.Header.Get("Retry-After") != "1") {
+		t.Errorf("rate limited request = %d, Retry-After %q", r.StatusCode, r.Header.Get("Retry-After"))
+	}
+	if code, body := get(t, s, "/profiles/76561197960287930/?xml=1"); (code != http.StatusOK) || !strings.Contains(body, "player") {
+		t.Errorf("request after the rate limit = %d, %q", code, body)
+	}
+	if n := len(s.Requests()); n != 2 {
+		t.Errorf("Requests() has %d entries, want 2", n)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.SetDelay(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/api/appdetails?appids=10", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if response, err := s.Client().Do(r); err == nil {
+		response.Body.Close()
+		t.Errorf("delayed request succeeded with %d", response.StatusCode)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("cancelled request took %s", d)
+	}
+
+	s.SetDelay(0)
+	if code, _ := get(t, s, "/api/appdetails?appids=10"); code != http.StatusOK {
+		t.Errorf("request without a delay = %d", code)
+	}
+}
+
+func TestKeyMethods(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddProfile(&Profile{SteamID64: 76561197960287930, Name: "player", CustomURL: "gaben"})
+
+	if code, _ := get(t, s, "/ISteamUser/ResolveVanityURL/v1/?vanityurl=gaben"); code != http.StatusForbidden {
+		t.Errorf("request without a key = %d, want %d", code, http.StatusForbidden)
+	}
+	_, body := get(t, s, "/ISteamUser/ResolveVanityURL/v1/?key=x&vanityurl=GabeN")
+	if !strings.Contains(body, `"steamid":"76561197960287930"`) {
+		t.Errorf("ResolveVanityURL = %s", body)
+	}
+}
+
+func TestGroupPages(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddGroup(&Group{GroupID64: 103582791429521412, Name: "Valve", URL: "valve", Members: []int64{1, 2, 3}, PageSize: 2})
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/gid/103582791429521412/memberslistxml/?xml=1", []string{"<currentPage>1</currentPage>", "<steamID64>2</steamID64>", "<totalPages>2</totalPages>"}},
+		{"/groups/Valve/memberslistxml/?xml=1&p=2", []string{"<currentPage>2</currentPage>", "<steamID64>3</steamID64>"}},
+		{"/groups/unknown/memberslistxml/?xml=1", []string{"<error>"}},
+	}
+	for _, tt := range tests {
+		_, body := get(t, s, tt.path)
+		for _, w := range tt.want {
+			if !strings.Contains(body, w) {
+				t.Errorf("%s = %s, want %s", tt.path, body, w)
+			}
+		}
+	}
+}
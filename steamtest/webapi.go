@@ -0,0 +1,121 @@
+package steamtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/vultour/steamcli/api/endpoints"
+	"gitlab.com/vultour/steamcli/objects"
+)
+
+// isKeyMethod determines whether the path is a Web API method that needs a
+// key
+func isKeyMethod(path string) bool {
+	switch path {
+	case endpoints.ResolveVanityURL, endpoints.GetPlayerSummaries, endpoints.GetOwnedGames:
+		return true
+	}
+	return false
+}
+
+// serveWebAPI writes the response of a Web API method that needs a key,
+// requests without one are forbidden like they are by Steam
+func (s *Server) serveWebAPI(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("key") == "" {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, "<html><head><title>Forbidden</title></head><body><h1>Forbidden</h1></body></html>")
+		return
+	}
+
+	var response map[string]interface{}
+	switch strings.Trim(r.URL.Path, "/") {
+	case endpoints.ResolveVanityURL:
+		response = s.resolveVanity(q.Get("vanityurl"))
+	case endpoints.GetPlayerSummaries:
+		response = s.playerSummaries(strings.Split(q.Get("steamids"), ","))
+	case endpoints.GetOwnedGames:
+		id, _ := strconv.ParseInt(q.Get("steamid"), 10, 64)
+		response = s.ownedGames(id, q.Get("include_appinfo") == "1")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"response": response})
+}
+
+func (s *Server) resolveVanity(name string) map[string]interface{} {
+	s.mu.Lock()
+	id, ok := s.aliases[strings.ToLower(name)]
+	s.mu.Unlock()
+	if !ok {
+		return map[string]interface{}{"success": 42, "message": "No match"}
+	}
+	return map[string]interface{}{"success": 1, "steamid": strconv.FormatInt(id, 10)}
+}
+
+// playerSummaries returns the summaries of the known profiles, private
+// profiles only show their name and URL
+func (s *Server) playerSummaries(steamids []string) map[string]interface{} {
+	players := make([]map[string]interface{}, 0, len(steamids))
+	for _, v := range steamids {
+		id, _ := strconv.ParseInt(v, 10, 64)
+		s.mu.Lock()
+		p, ok := s.profiles[id]
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		player := map[string]interface{}{
+			"steamid":                  strconv.FormatInt(p.SteamID64, 10),
+			"communityvisibilitystate": objects.VisibilityPublic,
+			"personaname":              p.Name,
+			"profileurl":               fmt.Sprintf("%s/%s/%d/", s.URL, endpoints.ID, p.SteamID64),
+		}
+		if p.CustomURL != "" {
+			player["profileurl"] = fmt.Sprintf("%s/%s/%s/", s.URL, endpoints.Alias, p.CustomURL)
+		}
+		if p.Private {
+			player["communityvisibilitystate"] = objects.VisibilityPrivate
+		} else {
+			since := p.MemberSince
+			if since.IsZero() {
+				since = time.Date(2010, time.January, 1, 0, 0, 0, 0, time.UTC)
+			}
+			player["timecreated"] = since.Unix()
+		}
+		players = append(players, player)
+	}
+	return map[string]interface{}{"players": players}
+}
+
+// ownedGames returns the games of the profile, private game details and
+// unknown profiles get an empty response like they do from Steam
+func (s *Server) ownedGames(id int64, appinfo bool) map[string]interface{} {
+	s.mu.Lock()
+	p, ok := s.profiles[id]
+	s.mu.Unlock()
+	if !ok || p.Private || p.GamesPrivate {
+		return map[string]interface{}{}
+	}
+
+	games := make([]map[string]interface{}, 0, len(p.Games))
+	for _, g := range p.Games {
+		game := map[string]interface{}{
+			"appid":            g.AppID,
+			"playtime_forever": int(g.HoursTotal * 60),
+		}
+		if appinfo {
+			game["name"] = g.Name
+		}
+		if g.HoursTwoWeeks > 0 {
+			game["playtime_2weeks"] = int(g.HoursTwoWeeks * 60)
+		}
+		games = append(games, game)
+	}
+	return map[string]interface{}{"game_count": len(games), "games": games}
+}